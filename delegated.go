@@ -0,0 +1,248 @@
+// Parsing of the RIR statistics exchange format, the delegated and delegated-extended files
+// published by AFRINIC, APNIC, ARIN, LACNIC and RIPE NCC:
+// https://www.apnic.net/about-apnic/corporate-documents/documents/resource-guidelines/rir-statistics-exchange-format/.
+
+package cidrman
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// delegatedDateLayout is the layout of the date field in delegated files.
+const delegatedDateLayout = "20060102"
+
+// DelegatedRecord is an IPv4 or IPv6 record from a delegated file.
+type DelegatedRecord struct {
+	Registry    string
+	CountryCode string
+	// Type is "ipv4" or "ipv6".
+	Type  string
+	Start net.IP
+	// Value is the number of addresses for IPv4 and the prefix length for IPv6.
+	Value uint64
+	// Date is the allocation or assignment date, the zero time when not set.
+	Date time.Time
+	// Status is "allocated", "assigned", "available" or "reserved".
+	Status string
+	// OpaqueID is the opaque id of delegated-extended files, empty otherwise.
+	OpaqueID string
+	// Nets are the CIDR blocks of the record.
+	Nets []*net.IPNet
+}
+
+// parseDelegatedRecord parses the fields of an IPv4 or IPv6 record line.
+func parseDelegatedRecord(fields []string) (*DelegatedRecord, error) {
+	if len(fields) < 7 {
+		return nil, fmt.Errorf("Invalid delegated record: %s", strings.Join(fields, "|"))
+	}
+
+	record := &DelegatedRecord{
+		Registry:    fields[0],
+		CountryCode: strings.ToUpper(fields[1]),
+		Type:        fields[2],
+		Status:      fields[6],
+	}
+	if len(fields) > 7 {
+		record.OpaqueID = fields[7]
+	}
+	if fields[5] != "" && fields[5] != "00000000" {
+		date, err := time.Parse(delegatedDateLayout, fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid delegated date: %s", fields[5])
+		}
+		record.Date = date
+	}
+	value, err := strconv.ParseUint(fields[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid delegated value: %s", fields[4])
+	}
+	record.Value = value
+	record.Start = net.ParseIP(fields[3])
+	if record.Start == nil {
+		return nil, fmt.Errorf("Invalid IP address: %s", fields[3])
+	}
+
+	switch record.Type {
+	case "ipv4":
+		// IPv4 records are a start address and a count, not always aligned as a CIDR block
+		start4 := record.Start.To4()
+		if start4 == nil {
+			return nil, fmt.Errorf("Invalid IPv4 address: %s", fields[3])
+		}
+		lo := uint64(ipv4ToUInt32(start4))
+		if value == 0 || lo+value-1 > math.MaxUint32 {
+			return nil, fmt.Errorf("Invalid delegated count: %s|%d", fields[3], value)
+		}
+		record.Start = start4
+		record.Nets, err = IPRangeToIPNets(start4, uint32ToIPV4(uint32(lo+value-1)))
+		if err != nil {
+			return nil, err
+		}
+	case "ipv6":
+		if record.Start.To4() != nil || value > widthUInt128 {
+			return nil, fmt.Errorf("Invalid delegated IPv6 prefix: %s/%d", fields[3], value)
+		}
+		_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", fields[3], value))
+		if err != nil {
+			return nil, err
+		}
+		record.Nets = []*net.IPNet{network}
+	}
+
+	return record, nil
+}
+
+// ParseDelegated parses a delegated or delegated-extended file and returns the IPv4 and IPv6 records.
+// The version and summary lines, comments and asn records are skipped.
+func ParseDelegated(r io.Reader) ([]*DelegatedRecord, error) {
+	var records []*DelegatedRecord
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		// Skip the version line, summary lines and asn records
+		if len(fields) < 6 || fields[5] == "summary" {
+			continue
+		}
+		if fields[2] != "ipv4" && fields[2] != "ipv6" {
+			continue
+		}
+
+		record, err := parseDelegatedRecord(fields)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %s", lineNumber, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// DelegatedFilter selects delegated records. Empty lists and zero times match all records.
+// Registries, country codes and statuses are compared case-insensitively.
+type DelegatedFilter struct {
+	Registries   []string
+	CountryCodes []string
+	Statuses     []string
+	// From and To select records with a date in the range, both inclusive.
+	From time.Time
+	To   time.Time
+}
+
+// matchAny reports whether the value is in the list, or the list is empty.
+func matchAny(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the record is selected by the filter.
+func (f DelegatedFilter) Match(record *DelegatedRecord) bool {
+	if !matchAny(f.Registries, record.Registry) ||
+		!matchAny(f.CountryCodes, record.CountryCode) ||
+		!matchAny(f.Statuses, record.Status) {
+		return false
+	}
+	if !f.From.IsZero() && (record.Date.IsZero() || record.Date.Before(f.From)) {
+		return false
+	}
+	if !f.To.IsZero() && (record.Date.IsZero() || record.Date.After(f.To)) {
+		return false
+	}
+	return true
+}
+
+// DelegatedIPNets returns the merged networks of the delegated records selected by the filter.
+func DelegatedIPNets(records []*DelegatedRecord, filter DelegatedFilter) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0)
+	for _, record := range records {
+		if filter.Match(record) {
+			nets = append(nets, record.Nets...)
+		}
+	}
+
+	return MergeIPNets(nets)
+}
+
+// DelegatedIPNetsByCountry returns the merged networks of the delegated records selected by the filter,
+// per country code. Records without a country code, like available space, are listed under "".
+func DelegatedIPNetsByCountry(records []*DelegatedRecord, filter DelegatedFilter) (map[string][]*net.IPNet, error) {
+	countries := make(map[string][]*net.IPNet)
+	for _, record := range records {
+		if filter.Match(record) {
+			countries[record.CountryCode] = append(countries[record.CountryCode], record.Nets...)
+		}
+	}
+
+	for country, nets := range countries {
+		merged, err := MergeIPNets(nets)
+		if err != nil {
+			return nil, err
+		}
+		countries[country] = merged
+	}
+	return countries, nil
+}
+
+// DelegatedIPNetsByRegistry returns the merged networks of the delegated records selected by the filter,
+// per registry.
+func DelegatedIPNetsByRegistry(records []*DelegatedRecord, filter DelegatedFilter) (map[string][]*net.IPNet, error) {
+	registries := make(map[string][]*net.IPNet)
+	for _, record := range records {
+		if filter.Match(record) {
+			registries[record.Registry] = append(registries[record.Registry], record.Nets...)
+		}
+	}
+
+	for registry, nets := range registries {
+		merged, err := MergeIPNets(nets)
+		if err != nil {
+			return nil, err
+		}
+		registries[registry] = merged
+	}
+	return registries, nil
+}
+
+// DelegatedCIDRs parses a delegated or delegated-extended file and returns the merged CIDRs
+// of the records selected by the filter.
+// Example:
+//     cidrs, err := DelegatedCIDRs(file, DelegatedFilter{CountryCodes: []string{"SE"}, Statuses: []string{"allocated", "assigned"}})
+func DelegatedCIDRs(r io.Reader, filter DelegatedFilter) ([]string, error) {
+	records, err := ParseDelegated(r)
+	if err != nil {
+		return nil, err
+	}
+	nets, err := DelegatedIPNets(records, filter)
+	if err != nil {
+		return nil, err
+	}
+	// Handle the situation where no records were selected
+	if len(nets) == 0 {
+		return make([]string, 0), nil
+	}
+
+	return ipNets(nets).toCIDRs(), nil
+}
@@ -0,0 +1,185 @@
+// go test -v -run="TestDelegatedCIDRs"
+
+package cidrman
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDelegated = `# Delegated-extended test file
+2|ripencc|20240101|6|19830705|20231231|+0100
+ripencc|*|ipv4|*|4|summary
+ripencc|*|ipv6|*|2|summary
+ripencc|*|asn|*|1|summary
+ripencc|SE|asn|1653|1|19930901|allocated|aaa
+ripencc|SE|ipv4|192.36.0.0|768|19930101|allocated|aaa
+ripencc|se|ipv4|192.36.3.0|256|20050101|assigned|bbb
+ripencc|NO|ipv4|193.0.0.0|1000|20100101|allocated|ccc
+ripencc||ipv4|195.0.0.0|256||available|
+ripencc|SE|ipv6|2001:6b0::|32|19990101|allocated|aaa
+ripencc|NO|ipv6|2001:700::|32|20000101|allocated|ccc
+`
+
+func TestDelegatedCIDRs(t *testing.T) {
+	type TestCase struct {
+		Input  string
+		Filter DelegatedFilter
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  "",
+			Filter: DelegatedFilter{},
+			Output: []string{},
+			Error:  false,
+		},
+		{
+			Input:  "ripencc|SE|ipv4|192.36.0.0|0|19930101|allocated",
+			Filter: DelegatedFilter{},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "ripencc|SE|ipv4|255.255.255.0|257|19930101|allocated",
+			Filter: DelegatedFilter{},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "ripencc|SE|ipv6|2001:6b0::|129|19930101|allocated",
+			Filter: DelegatedFilter{},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "ripencc|SE|ipv4|192.36.0.0|256|1993-01-01|allocated",
+			Filter: DelegatedFilter{},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  testDelegated,
+			Filter: DelegatedFilter{},
+			Output: []string{
+				"192.36.0.0/22",
+				"193.0.0.0/23",
+				"193.0.2.0/24",
+				"193.0.3.0/25",
+				"193.0.3.128/26",
+				"193.0.3.192/27",
+				"193.0.3.224/29",
+				"195.0.0.0/24",
+				"2001:6b0::/32",
+				"2001:700::/32",
+			},
+			Error: false,
+		},
+		{
+			Input:  testDelegated,
+			Filter: DelegatedFilter{CountryCodes: []string{"se"}},
+			Output: []string{
+				"192.36.0.0/22",
+				"2001:6b0::/32",
+			},
+			Error: false,
+		},
+		{
+			Input:  testDelegated,
+			Filter: DelegatedFilter{CountryCodes: []string{"SE"}, Statuses: []string{"allocated"}},
+			Output: []string{
+				"192.36.0.0/23",
+				"192.36.2.0/24",
+				"2001:6b0::/32",
+			},
+			Error: false,
+		},
+		{
+			Input:  testDelegated,
+			Filter: DelegatedFilter{Statuses: []string{"available"}},
+			Output: []string{
+				"195.0.0.0/24",
+			},
+			Error: false,
+		},
+		{
+			Input: testDelegated,
+			Filter: DelegatedFilter{
+				From: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			Output: []string{
+				"192.36.3.0/24",
+				"2001:700::/32",
+			},
+			Error: false,
+		},
+		{
+			Input:  testDelegated,
+			Filter: DelegatedFilter{Registries: []string{"arin"}},
+			Output: []string{},
+			Error:  false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := DelegatedCIDRs(strings.NewReader(testCase.Input), testCase.Filter)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("DelegatedCIDRs(%#v, %+v) failed: %s", testCase.Input, testCase.Filter, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("DelegatedCIDRs(%#v, %+v) expected error, got: %#v", testCase.Input, testCase.Filter, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("DelegatedCIDRs(%#v, %+v) expected: %#v, got: %#v", testCase.Input, testCase.Filter, testCase.Output, output)
+		}
+	}
+}
+
+func TestDelegatedIPNetsByCountry(t *testing.T) {
+	records, err := ParseDelegated(strings.NewReader(testDelegated))
+	if err != nil {
+		t.Fatalf("ParseDelegated() failed: %s", err.Error())
+	}
+
+	countries, err := DelegatedIPNetsByCountry(records, DelegatedFilter{Registries: []string{"RIPENCC"}})
+	if err != nil {
+		t.Fatalf("DelegatedIPNetsByCountry() failed: %s", err.Error())
+	}
+	expected := map[string][]string{
+		"": {"195.0.0.0/24"},
+		"NO": {
+			"193.0.0.0/23",
+			"193.0.2.0/24",
+			"193.0.3.0/25",
+			"193.0.3.128/26",
+			"193.0.3.192/27",
+			"193.0.3.224/29",
+			"2001:700::/32",
+		},
+		"SE": {"192.36.0.0/22", "2001:6b0::/32"},
+	}
+	output := make(map[string][]string)
+	for country, nets := range countries {
+		output[country] = ipNets(nets).toCIDRs()
+	}
+	if !reflect.DeepEqual(expected, output) {
+		t.Errorf("DelegatedIPNetsByCountry() expected: %#v, got: %#v", expected, output)
+	}
+
+	registries, err := DelegatedIPNetsByRegistry(records, DelegatedFilter{CountryCodes: []string{"SE"}})
+	if err != nil {
+		t.Fatalf("DelegatedIPNetsByRegistry() failed: %s", err.Error())
+	}
+	if output := ipNets(registries["ripencc"]).toCIDRs(); !reflect.DeepEqual(expected["SE"], output) || len(registries) != 1 {
+		t.Errorf("DelegatedIPNetsByRegistry() expected: %#v, got: %#v", expected["SE"], output)
+	}
+}
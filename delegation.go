@@ -0,0 +1,131 @@
+package cidrman
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// DelegationPool is an IPv6 pool of delegated prefixes of one length, like the /56s of a /48
+// handed out with DHCPv6 prefix delegation. Subscriber n gets the nth prefix of the pool,
+// counted from 0, computed directly with 128-bit arithmetic.
+// Example:
+//     pool, err := NewDelegationPool("2001:db8::/48", 56)
+//     prefix, err := pool.Prefix(3)  // 2001:db8:0:300::/56
+//     index, err := pool.Index(prefix)  // 3
+type DelegationPool struct {
+	pool   *net.IPNet
+	first  *big.Int
+	length int
+	size   *big.Int
+}
+
+// NibbleAligned reports whether a prefix length is on a nibble boundary, a multiple of 4,
+// so that each prefix has its own ip6.arpa reverse DNS zone.
+func NibbleAligned(length int) bool {
+	return length%4 == 0
+}
+
+// NibbleLength rounds a prefix length up to the next nibble boundary, like 57 to 60.
+func NibbleLength(length int) int {
+	return (length + 3) &^ 3
+}
+
+// NewDelegationPool returns the pool of the delegated prefixes of the given length in an IPv6 CIDR block.
+func NewDelegationPool(pool string, length int) (*DelegationPool, error) {
+	_, network, err := net.ParseCIDR(pool)
+	if err != nil {
+		return nil, err
+	}
+	if network.IP.To4() != nil {
+		return nil, fmt.Errorf("Not an IPv6 pool: %s", pool)
+	}
+	ones, _ := network.Mask.Size()
+	if length < ones || length > widthUInt128 {
+		return nil, fmt.Errorf("Invalid delegated prefix length: /%d in %s", length, network)
+	}
+
+	return &DelegationPool{
+		pool:   network,
+		first:  ipv6ToUInt128(network.IP.To16()),
+		length: length,
+		size:   big.NewInt(0).Lsh(big.NewInt(1), uint(length-ones)),
+	}, nil
+}
+
+// Pool returns the CIDR block of the pool.
+func (p *DelegationPool) Pool() *net.IPNet {
+	return p.pool
+}
+
+// Length returns the prefix length of the delegated prefixes.
+func (p *DelegationPool) Length() int {
+	return p.length
+}
+
+// Size returns the number of delegated prefixes in the pool.
+func (p *DelegationPool) Size() *big.Int {
+	return copyUInt128(p.size)
+}
+
+// NibbleAligned reports whether both the pool and the delegated prefixes are on nibble boundaries.
+func (p *DelegationPool) NibbleAligned() bool {
+	ones, _ := p.pool.Mask.Size()
+	return NibbleAligned(ones) && NibbleAligned(p.length)
+}
+
+// Prefix returns the delegated prefix of subscriber index n, the nth prefix of the pool.
+func (p *DelegationPool) Prefix(n uint64) (*net.IPNet, error) {
+	index := big.NewInt(0).SetUint64(n)
+	if index.Cmp(p.size) >= 0 {
+		return nil, fmt.Errorf("Subscriber index out of range: %d of %v in %s", n, p.size, p.pool)
+	}
+
+	addr := index.Lsh(index, uint(widthUInt128-p.length))
+	addr.Add(addr, p.first)
+	return &net.IPNet{IP: uint128ToIPV6(addr), Mask: net.CIDRMask(p.length, widthUInt128)}, nil
+}
+
+// Prefixes returns count delegated prefixes of the pool starting at subscriber index start,
+// fewer at the end of the pool.
+func (p *DelegationPool) Prefixes(start, count uint64) ([]*net.IPNet, error) {
+	var prefixes []*net.IPNet
+	for i := uint64(0); i < count; i++ {
+		if big.NewInt(0).SetUint64(start+i).Cmp(p.size) >= 0 || start+i < start {
+			break
+		}
+		prefix, err := p.Prefix(start + i)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// Index returns the subscriber index of a delegated prefix of the pool.
+// The prefix must not have any host bits set.
+func (p *DelegationPool) Index(prefix *net.IPNet) (uint64, error) {
+	ones, bits := prefix.Mask.Size()
+	if bits != widthUInt128 || prefix.IP.To4() != nil || ones != p.length || !p.pool.Contains(prefix.IP) ||
+		!prefix.IP.Equal(prefix.IP.Mask(prefix.Mask)) {
+		return 0, fmt.Errorf("Not a delegated prefix of %s/%d: %s", p.pool, p.length, prefix)
+	}
+
+	index := ipv6ToUInt128(prefix.IP.To16())
+	index.Sub(index, p.first)
+	index.Rsh(index, uint(widthUInt128-p.length))
+	if !index.IsUint64() {
+		return 0, fmt.Errorf("Subscriber index out of range: %v", index)
+	}
+	return index.Uint64(), nil
+}
+
+// IndexCIDR returns the subscriber index of a delegated prefix of the pool, written as a CIDR block.
+func (p *DelegationPool) IndexCIDR(cidr string) (uint64, error) {
+	ip, prefix, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return 0, err
+	}
+	return p.Index(&net.IPNet{IP: ip, Mask: prefix.Mask})
+}
@@ -0,0 +1,168 @@
+// go test -v -run="TestDelegationPool|TestNibble"
+
+package cidrman
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDelegationPool(t *testing.T) {
+	type TestCase struct {
+		Pool    string
+		Length  int
+		Size    string
+		Nibble  bool
+		Indexes []uint64
+		Output  []string
+		Error   bool
+	}
+
+	testCases := []TestCase{
+		{
+			Pool:    "2001:db8::/48",
+			Length:  56,
+			Size:    "256",
+			Nibble:  true,
+			Indexes: []uint64{0, 1, 3, 255},
+			Output:  []string{"2001:db8::/56", "2001:db8:0:100::/56", "2001:db8:0:300::/56", "2001:db8:0:ff00::/56"},
+			Error:   false,
+		},
+		{
+			Pool:    "2001:db8::/32",
+			Length:  60,
+			Size:    "268435456",
+			Nibble:  true,
+			Indexes: []uint64{0, 17, 268435455},
+			Output:  []string{"2001:db8::/60", "2001:db8:0:110::/60", "2001:db8:ffff:fff0::/60"},
+			Error:   false,
+		},
+		{
+			Pool:    "2001:db8:0:80::/57",
+			Length:  62,
+			Size:    "32",
+			Nibble:  false,
+			Indexes: []uint64{1, 31},
+			Output:  []string{"2001:db8:0:84::/62", "2001:db8:0:fc::/62"},
+			Error:   false,
+		},
+		{
+			Pool:   "::/0",
+			Length: 128,
+			Size:   "340282366920938463463374607431768211456",
+			Nibble: true,
+			Indexes: []uint64{
+				18446744073709551615,
+			},
+			Output: []string{"::ffff:ffff:ffff:ffff/128"},
+			Error:  false,
+		},
+		{
+			Pool:   "2001:db8::/48",
+			Length: 44,
+			Error:  true,
+		},
+		{
+			Pool:   "10.0.0.0/8",
+			Length: 16,
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		pool, err := NewDelegationPool(testCase.Pool, testCase.Length)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("NewDelegationPool(%#v, %d) failed: %s", testCase.Pool, testCase.Length, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("NewDelegationPool(%#v, %d) expected error, got: %#v", testCase.Pool, testCase.Length, pool)
+			continue
+		}
+		if size := pool.Size().String(); size != testCase.Size {
+			t.Errorf("NewDelegationPool(%#v, %d).Size() expected: %s, got: %s", testCase.Pool, testCase.Length, testCase.Size, size)
+		}
+		if nibble := pool.NibbleAligned(); nibble != testCase.Nibble {
+			t.Errorf("NewDelegationPool(%#v, %d).NibbleAligned() expected: %v, got: %v", testCase.Pool, testCase.Length, testCase.Nibble, nibble)
+		}
+
+		var output []string
+		for i, index := range testCase.Indexes {
+			prefix, err := pool.Prefix(index)
+			if err != nil {
+				t.Errorf("NewDelegationPool(%#v, %d).Prefix(%d) failed: %s", testCase.Pool, testCase.Length, index, err.Error())
+				continue
+			}
+			output = append(output, prefix.String())
+
+			back, err := pool.IndexCIDR(testCase.Output[i])
+			if err != nil || back != index {
+				t.Errorf("NewDelegationPool(%#v, %d).IndexCIDR(%s) expected: %d, got: %d, %v", testCase.Pool, testCase.Length, testCase.Output[i], index, back, err)
+			}
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("NewDelegationPool(%#v, %d).Prefix expected: %#v, got: %#v", testCase.Pool, testCase.Length, testCase.Output, output)
+		}
+	}
+}
+
+func TestDelegationPoolRange(t *testing.T) {
+	pool, err := NewDelegationPool("2001:db8::/62", 64)
+	if err != nil {
+		t.Fatalf("NewDelegationPool failed: %s", err.Error())
+	}
+
+	prefixes, err := pool.Prefixes(2, 5)
+	if err != nil {
+		t.Fatalf("Prefixes(2, 5) failed: %s", err.Error())
+	}
+	output := ipNets(prefixes).toCIDRs()
+	expected := []string{"2001:db8:0:2::/64", "2001:db8:0:3::/64"}
+	if !reflect.DeepEqual(expected, output) {
+		t.Errorf("Prefixes(2, 5) expected: %#v, got: %#v", expected, output)
+	}
+
+	if _, err := pool.Prefix(4); err == nil {
+		t.Errorf("Prefix(4) expected error")
+	}
+	for _, cidr := range []string{"2001:db8:0:4::/64", "2001:db8::/63", "2001:db8::1/64", "10.0.0.0/8"} {
+		if index, err := pool.IndexCIDR(cidr); err == nil {
+			t.Errorf("IndexCIDR(%s) expected error, got: %d", cidr, index)
+		}
+	}
+	_, network, _ := net.ParseCIDR("2001:db8:0:1::/64")
+	if index, err := pool.Index(network); err != nil || index != 1 {
+		t.Errorf("Index(%s) expected: 1, got: %d, %v", network, index, err)
+	}
+	network = &net.IPNet{IP: net.ParseIP("2001:db8:0:1::1"), Mask: network.Mask}
+	if index, err := pool.Index(network); err == nil {
+		t.Errorf("Index(%s) expected error, got: %d", network, index)
+	}
+}
+
+func TestNibble(t *testing.T) {
+	type TestCase struct {
+		Length  int
+		Aligned bool
+		Nibble  int
+	}
+
+	testCases := []TestCase{
+		{Length: 48, Aligned: true, Nibble: 48},
+		{Length: 56, Aligned: true, Nibble: 56},
+		{Length: 57, Aligned: false, Nibble: 60},
+		{Length: 63, Aligned: false, Nibble: 64},
+	}
+
+	for _, testCase := range testCases {
+		if aligned := NibbleAligned(testCase.Length); aligned != testCase.Aligned {
+			t.Errorf("NibbleAligned(%d) expected: %v, got: %v", testCase.Length, testCase.Aligned, aligned)
+		}
+		if nibble := NibbleLength(testCase.Length); nibble != testCase.Nibble {
+			t.Errorf("NibbleLength(%d) expected: %d, got: %d", testCase.Length, testCase.Nibble, nibble)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cidrman
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// interfacePrefix returns the IPv6 address of a /64 prefix, for SLAAC interface identifiers.
+func interfacePrefix(prefix *net.IPNet) (net.IP, error) {
+	ones, bits := prefix.Mask.Size()
+	ip := prefix.IP.To16()
+	if bits != widthUInt128 || ones != 64 || ip == nil || prefix.IP.To4() != nil {
+		return nil, fmt.Errorf("Not an IPv6 /64 prefix: %s", prefix)
+	}
+	return ip.Mask(prefix.Mask), nil
+}
+
+// EUI64Address returns the SLAAC address of a MAC address in a /64 prefix, with the modified
+// EUI-64 interface identifier of RFC 4291 appendix A: ff:fe inserted in the middle of a 48-bit MAC
+// address, or a 64-bit EUI-64 as is, and the universal/local bit inverted.
+// Example:
+//     mac, _ := net.ParseMAC("00:00:5e:00:53:01")
+//     ip, err := EUI64Address(prefix, mac)  // 2001:db8::200:5eff:fe00:5301 in 2001:db8::/64
+func EUI64Address(prefix *net.IPNet, mac net.HardwareAddr) (net.IP, error) {
+	ip, err := interfacePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(mac) {
+	case 6:
+		copy(ip[8:], mac[:3])
+		ip[11], ip[12] = 0xff, 0xfe
+		copy(ip[13:], mac[3:])
+	case 8:
+		copy(ip[8:], mac)
+	default:
+		return nil, fmt.Errorf("Invalid MAC address: %s", mac)
+	}
+	ip[8] ^= 0x02
+	return ip, nil
+}
+
+// EUI64ToMAC returns the 48-bit MAC address of an IPv6 address with a modified EUI-64 interface
+// identifier, the reverse of EUI64Address. The interface identifier must have ff:fe in the middle.
+func EUI64ToMAC(ip net.IP) (net.HardwareAddr, error) {
+	ip6 := ip.To16()
+	if ip6 == nil || ip.To4() != nil || ip6[11] != 0xff || ip6[12] != 0xfe {
+		return nil, fmt.Errorf("Not an EUI-64 address: %s", ip)
+	}
+
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, ip6[8:11])
+	copy(mac[3:], ip6[13:])
+	mac[0] ^= 0x02
+	return mac, nil
+}
+
+// reservedInterfaceID reports whether an interface identifier is reserved (RFC 5453): the
+// subnet-router anycast identifier, the identifiers of the IANA Ethernet block including the
+// proxy Mobile IPv6 identifier, and the reserved subnet anycast identifiers.
+func reservedInterfaceID(id uint64) bool {
+	return id == 0 ||
+		(id >= 0x02005efffe000000 && id <= 0x02005efffe005213) ||
+		(id >= 0xfdffffffffffff80 && id <= 0xfdffffffffffffff)
+}
+
+// StablePrivacyAddress returns the RFC 7217 stable, semantically opaque SLAAC address of an
+// interface in a /64 prefix. The interface identifier is the first 64 bits of
+// SHA-256(Prefix | Net_Iface | Network_ID | DAD_Counter | secret_key). The network ID, like
+// an SSID, may be empty. The DAD counter starts at 0 and is incremented by the caller after
+// a duplicate address is detected, and here while the identifier is reserved.
+// Example:
+//     ip, err := StablePrivacyAddress(prefix, "eth0", nil, 0, secret)
+func StablePrivacyAddress(prefix *net.IPNet, iface string, networkID []byte, dadCounter uint8, secret []byte) (net.IP, error) {
+	ip, err := interfacePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("Secret key too short: %d bytes, at least 16", len(secret))
+	}
+
+	for {
+		h := sha256.New()
+		h.Write(ip[:8])
+		h.Write([]byte(iface))
+		h.Write(networkID)
+		h.Write([]byte{dadCounter})
+		h.Write(secret)
+		id := binary.BigEndian.Uint64(h.Sum(nil))
+		if !reservedInterfaceID(id) {
+			binary.BigEndian.PutUint64(ip[8:], id)
+			return ip, nil
+		}
+		if dadCounter == 0xff {
+			return nil, fmt.Errorf("No interface identifier for %s in %s", iface, prefix)
+		}
+		dadCounter++
+	}
+}
@@ -0,0 +1,215 @@
+// go test -v -run="TestEUI64|TestReservedInterfaceID|TestStablePrivacyAddress"
+
+package cidrman
+
+import (
+	"net"
+	"testing"
+)
+
+func TestEUI64Address(t *testing.T) {
+	type TestCase struct {
+		Prefix string
+		MAC    string
+		Output string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Prefix: "2001:db8::/64",
+			MAC:    "00:00:5e:00:53:01",
+			Output: "2001:db8::200:5eff:fe00:5301",
+			Error:  false,
+		},
+		{
+			Prefix: "2001:db8:1:2::/64",
+			MAC:    "02:00:5e:10:00:00:00:01",
+			Output: "2001:db8:1:2:0:5e10:0:1",
+			Error:  false,
+		},
+		{
+			Prefix: "2001:db8::/48",
+			MAC:    "00:00:5e:00:53:01",
+			Error:  true,
+		},
+		{
+			Prefix: "192.0.2.0/24",
+			MAC:    "00:00:5e:00:53:01",
+			Error:  true,
+		},
+		{
+			Prefix: "2001:db8::/64",
+			MAC:    "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01",
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, prefix, _ := net.ParseCIDR(testCase.Prefix)
+		mac, _ := net.ParseMAC(testCase.MAC)
+		ip, err := EUI64Address(prefix, mac)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("EUI64Address(%s, %s) failed: %s", testCase.Prefix, testCase.MAC, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("EUI64Address(%s, %s) expected error, got: %s", testCase.Prefix, testCase.MAC, ip)
+			continue
+		}
+		if ip.String() != testCase.Output {
+			t.Errorf("EUI64Address(%s, %s) expected: %s, got: %s", testCase.Prefix, testCase.MAC, testCase.Output, ip)
+		}
+	}
+}
+
+func TestEUI64ToMAC(t *testing.T) {
+	type TestCase struct {
+		Input  string
+		Output string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  "2001:db8::200:5eff:fe00:5301",
+			Output: "00:00:5e:00:53:01",
+			Error:  false,
+		},
+		{
+			Input:  "fe80::ff:fe00:1",
+			Output: "02:00:00:00:00:01",
+			Error:  false,
+		},
+		{
+			Input: "2001:db8::5e93:5c11:e9aa:cd8c",
+			Error: true,
+		},
+		{
+			Input: "192.0.2.1",
+			Error: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		mac, err := EUI64ToMAC(net.ParseIP(testCase.Input))
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("EUI64ToMAC(%s) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("EUI64ToMAC(%s) expected error, got: %s", testCase.Input, mac)
+			continue
+		}
+		if mac.String() != testCase.Output {
+			t.Errorf("EUI64ToMAC(%s) expected: %s, got: %s", testCase.Input, testCase.Output, mac)
+		}
+	}
+}
+
+func TestReservedInterfaceID(t *testing.T) {
+	type TestCase struct {
+		ID       uint64
+		Reserved bool
+	}
+
+	testCases := []TestCase{
+		{0x0000000000000000, true},
+		{0x0000000000000001, false},
+		{0x02005efffdffffff, false},
+		{0x02005efffe000000, true},
+		{0x02005efffe005212, true},
+		{0x02005efffe005213, true},
+		{0x02005efffe005214, false},
+		{0xfdffffffffffff7f, false},
+		{0xfdffffffffffff80, true},
+		{0xfdffffffffffffff, true},
+		{0xfe00000000000000, false},
+		{0xffffffffffffffff, false},
+	}
+
+	for _, testCase := range testCases {
+		if reserved := reservedInterfaceID(testCase.ID); reserved != testCase.Reserved {
+			t.Errorf("reservedInterfaceID(%#x) expected: %t, got: %t", testCase.ID, testCase.Reserved, reserved)
+		}
+	}
+}
+
+func TestStablePrivacyAddress(t *testing.T) {
+	type TestCase struct {
+		Prefix     string
+		Iface      string
+		NetworkID  []byte
+		DADCounter uint8
+		Secret     []byte
+		Output     string
+		Error      bool
+	}
+
+	secret := []byte("0123456789abcdef")
+	testCases := []TestCase{
+		{
+			Prefix:     "2001:db8::/64",
+			Iface:      "eth0",
+			NetworkID:  nil,
+			DADCounter: 0,
+			Secret:     secret,
+			Output:     "2001:db8::5e93:5c11:e9aa:cd8c",
+			Error:      false,
+		},
+		{
+			Prefix:     "2001:db8::/64",
+			Iface:      "eth0",
+			NetworkID:  nil,
+			DADCounter: 1,
+			Secret:     secret,
+			Output:     "2001:db8::cd8e:de34:3d37:4476",
+			Error:      false,
+		},
+		{
+			Prefix:     "2001:db8::/64",
+			Iface:      "eth0",
+			NetworkID:  []byte("office-wifi"),
+			DADCounter: 0,
+			Secret:     secret,
+			Output:     "2001:db8::a163:aded:4707:9848",
+			Error:      false,
+		},
+		{
+			Prefix:     "2001:db8::/56",
+			Iface:      "eth0",
+			DADCounter: 0,
+			Secret:     secret,
+			Error:      true,
+		},
+		{
+			Prefix:     "2001:db8::/64",
+			Iface:      "eth0",
+			DADCounter: 0,
+			Secret:     []byte("short"),
+			Error:      true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, prefix, _ := net.ParseCIDR(testCase.Prefix)
+		ip, err := StablePrivacyAddress(prefix, testCase.Iface, testCase.NetworkID, testCase.DADCounter, testCase.Secret)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("StablePrivacyAddress(%s, %s, %d) failed: %s", testCase.Prefix, testCase.Iface, testCase.DADCounter, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("StablePrivacyAddress(%s, %s, %d) expected error, got: %s", testCase.Prefix, testCase.Iface, testCase.DADCounter, ip)
+			continue
+		}
+		if ip.String() != testCase.Output {
+			t.Errorf("StablePrivacyAddress(%s, %s, %d) expected: %s, got: %s", testCase.Prefix, testCase.Iface, testCase.DADCounter, testCase.Output, ip)
+		}
+	}
+}
@@ -148,14 +148,15 @@ func (c cidrBlock4s) Swap(i, j int) {
 	c[i], c[j] = c[j], c[i]
 }
 
-// merge4 accepts a list of IPv4 networks and merges them into the smallest possible list of IPNets.
-// It merges adjacent subnets where possible, those contained within others and removes any duplicates.
-func merge4(blocks cidrBlock4s) ([]*net.IPNet, error) {
+// coalesce4 sorts a list of IPv4 blocks and coalesces overlapping and adjacent blocks.
+// The remaining blocks are returned in ascending order.
+func coalesce4(blocks cidrBlock4s) cidrBlock4s {
 	sort.Sort(blocks)
 
 	// Coalesce overlapping blocks.
 	for i := len(blocks) - 1; i > 0; i-- {
-		if blocks[i].first <= blocks[i-1].last+1 {
+		// A block ending at the last address has no next address to be adjacent to
+		if blocks[i-1].last == maxUInt32 || blocks[i].first <= blocks[i-1].last+1 {
 			blocks[i-1].last = blocks[i].last
 			if blocks[i].first < blocks[i-1].first {
 				blocks[i-1].first = blocks[i].first
@@ -164,12 +165,21 @@ func merge4(blocks cidrBlock4s) ([]*net.IPNet, error) {
 		}
 	}
 
-	var merged []*net.IPNet
+	n := 0
 	for _, block := range blocks {
-		if block == nil {
-			continue
+		if block != nil {
+			blocks[n] = block
+			n++
 		}
+	}
+	return blocks[:n]
+}
 
+// merge4 accepts a list of IPv4 networks and merges them into the smallest possible list of IPNets.
+// It merges adjacent subnets where possible, those contained within others and removes any duplicates.
+func merge4(blocks cidrBlock4s) ([]*net.IPNet, error) {
+	var merged []*net.IPNet
+	for _, block := range coalesce4(blocks) {
 		if err := splitRange4(0, 0, block.first, block.last, &merged); err != nil {
 			return nil, err
 		}
@@ -264,31 +274,26 @@ func subset4(blocks, subsets cidrBlock4s) ([]*net.IPNet, error) {
 			// Network-block inside subset-block, keep that network-block
 			i++
 		// From here on we have some sort of overlap
-		} else if blocks[i].first >= subsets[j].first {
-			// Network-block starts inside subset-block, adjust end of network-block
-			blocks[i].last = subsets[j].last
-			i++
-			j++
 		} else if blocks[i].last <= subsets[j].last {
 			// Network-block ends inside subset-block, adjust start of network-block
 			blocks[i].first = subsets[j].first
 			i++
 		} else {
-			// Subset-block inside network-block, adjust both start and end of network-block
-			blocks[i].first = subsets[j].first
-			// Check if next subset-block exists and starts with network-block
-			if j < len(subsets) - 1 && subsets[j+1].first < blocks[i].last {
-				// Make room for new network block
-				blocks = append(blocks, nil)
-				copy(blocks[i+1:], blocks[i:])
-				blocks[i] = new(cidrBlock4)
-				// update first half of the network-block (new)
+			// Network-block continues after subset-block, keep the overlap as a new network-block
+			// and continue with the rest of the network-block
+			//
+			// Make room for new network block
+			blocks = append(blocks, nil)
+			copy(blocks[i+1:], blocks[i:])
+			blocks[i] = new(cidrBlock4)
+			// Overlap with the subset-block (new)
+			blocks[i].first = blocks[i+1].first
+			if subsets[j].first > blocks[i].first {
 				blocks[i].first = subsets[j].first
-				// Update second half of the network-block (old)
-				blocks[i+1].first = subsets[j+1].first
 			}
-			// Finaly handle (first) network-block's last address
 			blocks[i].last = subsets[j].last
+			// Rest of the network-block (old)
+			blocks[i+1].first = subsets[j].last + 1
 			i++
 			j++
 		}
@@ -156,9 +156,9 @@ func (c cidrBlock6s) Swap(i, j int) {
 	c[i], c[j] = c[j], c[i]
 }
 
-// merge6 accepts a list of IPv6 networks and merges them into the smallest possible list of IPNets.
-// It merges adjacent subnets where possible, those contained within others and removes any duplicates.
-func merge6(blocks cidrBlock6s) ([]*net.IPNet, error) {
+// coalesce6 sorts a list of IPv6 blocks and coalesces overlapping and adjacent blocks.
+// The remaining blocks are returned in ascending order.
+func coalesce6(blocks cidrBlock6s) cidrBlock6s {
 	sort.Sort(blocks)
 
 	// Coalesce overlapping blocks.
@@ -174,12 +174,21 @@ func merge6(blocks cidrBlock6s) ([]*net.IPNet, error) {
 		}
 	}
 
-	var merged []*net.IPNet
+	n := 0
 	for _, block := range blocks {
-		if block == nil {
-			continue
+		if block != nil {
+			blocks[n] = block
+			n++
 		}
+	}
+	return blocks[:n]
+}
 
+// merge6 accepts a list of IPv6 networks and merges them into the smallest possible list of IPNets.
+// It merges adjacent subnets where possible, those contained within others and removes any duplicates.
+func merge6(blocks cidrBlock6s) ([]*net.IPNet, error) {
+	var merged []*net.IPNet
+	for _, block := range coalesce6(blocks) {
 		if err := splitRange6(big.NewInt(0), 0, block.first, block.last, &merged); err != nil {
 			return nil, err
 		}
@@ -278,31 +287,26 @@ func subset6(blocks, subsets cidrBlock6s) ([]*net.IPNet, error) {
 			// Network-block inside subset-block, keep that network-block
 			i++
 		// From here on we have some sort of overlap
-		} else if blocks[i].first.Cmp(subsets[j].first) >= 0 {
-			// Network-block starts inside subset-block, adjust end of network-block
-			blocks[i].last = subsets[j].last
-			i++
-			j++
 		} else if blocks[i].last.Cmp(subsets[j].last) <= 0 {
 			// Network-block ends inside subset-block, adjust start of network-block
 			blocks[i].first = subsets[j].first
 			i++
 		} else {
-			// Subset-block inside network-block, adjust both start and end of network-block
-			blocks[i].first = subsets[j].first
-			// Check if next subset-block exists and starts with network-block
-			if j < len(subsets) - 1 && subsets[j+1].first.Cmp(blocks[i].last) < 0 {
-				// Make room for new network block
-				blocks = append(blocks, nil)
-				copy(blocks[i+1:], blocks[i:])
-				blocks[i] = new(cidrBlock6)
-				// update first half of the network-block (new)
+			// Network-block continues after subset-block, keep the overlap as a new network-block
+			// and continue with the rest of the network-block
+			//
+			// Make room for new network block
+			blocks = append(blocks, nil)
+			copy(blocks[i+1:], blocks[i:])
+			blocks[i] = new(cidrBlock6)
+			// Overlap with the subset-block (new)
+			blocks[i].first = blocks[i+1].first
+			if subsets[j].first.Cmp(blocks[i].first) > 0 {
 				blocks[i].first = subsets[j].first
-				// Update second half of the network-block (old)
-				blocks[i+1].first = subsets[j+1].first
 			}
-			// Finaly handle (first) network-block's last address
 			blocks[i].last = subsets[j].last
+			// Rest of the network-block (old)
+			blocks[i+1].first = big.NewInt(0).Add(subsets[j].last, big.NewInt(1))
 			i++
 			j++
 		}
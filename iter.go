@@ -0,0 +1,164 @@
+package cidrman
+
+import (
+	"math/big"
+	"net"
+)
+
+// IterOptions controls how an Iter walks the addresses in a list of networks.
+type IterOptions struct {
+	// HostsOnly skips the network and broadcast address of each IPv4 CIDR block
+	// in the merged list. Blocks of /31 and /32 are kept whole (RFC 3021),
+	// IPv6 blocks have no broadcast address and are never trimmed.
+	HostsOnly bool
+	// Stride is the distance between two returned addresses, 0 and 1 return every address.
+	// The stride is counted across block boundaries, as if the list was one range.
+	Stride uint64
+	// Reverse walks the addresses from the highest to the lowest, IPv6 before IPv4.
+	Reverse bool
+}
+
+// Iter iterates over the addresses in a list of networks without expanding them up front.
+// Example:
+//     it, err := NewIter(nets, IterOptions{HostsOnly: true})
+//     for it.Next() {
+//         fmt.Println(it.IP())
+//     }
+type Iter struct {
+	// IPv4 blocks, stored as 128-bit intervals, come before the IPv6 blocks.
+	blocks  cidrBlock6s
+	count4  int
+	stride  *big.Int
+	reverse bool
+
+	index   int
+	cur     *big.Int
+	started bool
+	done    bool
+}
+
+// NewIter returns an iterator over the addresses in a list of mixed IP networks.
+// The networks are merged first, so every address is returned once and in order.
+func NewIter(nets []*net.IPNet, opts IterOptions) (*Iter, error) {
+	it := &Iter{
+		stride:  big.NewInt(1),
+		reverse: opts.Reverse,
+		cur:     big.NewInt(0),
+	}
+	if opts.Stride > 1 {
+		it.stride.SetUint64(opts.Stride)
+	}
+	if len(nets) == 0 {
+		it.done = true
+		return it, nil
+	}
+
+	nets, err := MergeIPNets(nets)
+	if err != nil {
+		return nil, err
+	}
+
+	// MergeIPNets returns the IPv4 networks before the IPv6 networks, both in order.
+	for _, net := range nets {
+		prefix, _ := net.Mask.Size()
+		ip4 := net.IP.To4()
+		if ip4 != nil {
+			block4 := newBlock4(ip4, net.Mask)
+			if opts.HostsOnly && prefix < widthUInt32-1 {
+				block4.first++
+				block4.last--
+			}
+			it.blocks = append(it.blocks, &cidrBlock6{
+				first: big.NewInt(0).SetUint64(uint64(block4.first)),
+				last:  big.NewInt(0).SetUint64(uint64(block4.last)),
+			})
+			it.count4++
+		} else {
+			it.blocks = append(it.blocks, newBlock6(net.IP.To16(), net.Mask))
+		}
+	}
+
+	return it, nil
+}
+
+// NewIterCIDRs returns an iterator over the addresses in a list of mixed CIDR blocks.
+func NewIterCIDRs(cidrs []string, opts IterOptions) (*Iter, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return NewIter(networks, opts)
+}
+
+// NewIterRange returns an iterator over the addresses between the start and end IP address.
+func NewIterRange(start, end net.IP, opts IterOptions) (*Iter, error) {
+	nets, err := IPRangeToIPNets(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIter(nets, opts)
+}
+
+// Next advances the iterator to the next address and reports whether there is one.
+func (it *Iter) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if !it.started {
+		it.started = true
+		if it.reverse {
+			it.index = len(it.blocks) - 1
+			it.cur.Set(it.blocks[it.index].last)
+		} else {
+			it.index = 0
+			it.cur.Set(it.blocks[it.index].first)
+		}
+		return true
+	}
+
+	if it.reverse {
+		it.cur.Sub(it.cur, it.stride)
+		// Carry what is left of the stride into the previous blocks
+		for it.cur.Cmp(it.blocks[it.index].first) < 0 {
+			under := big.NewInt(0).Sub(it.blocks[it.index].first, it.cur)
+			it.index--
+			if it.index < 0 {
+				it.done = true
+				return false
+			}
+			it.cur.Sub(it.blocks[it.index].last, under.Sub(under, big.NewInt(1)))
+		}
+	} else {
+		it.cur.Add(it.cur, it.stride)
+		// Carry what is left of the stride into the following blocks
+		for it.cur.Cmp(it.blocks[it.index].last) > 0 {
+			over := big.NewInt(0).Sub(it.cur, it.blocks[it.index].last)
+			it.index++
+			if it.index >= len(it.blocks) {
+				it.done = true
+				return false
+			}
+			it.cur.Add(it.blocks[it.index].first, over.Sub(over, big.NewInt(1)))
+		}
+	}
+
+	return true
+}
+
+// IP returns the current address of the iterator.
+func (it *Iter) IP() net.IP {
+	if !it.started || it.done {
+		return nil
+	}
+	if it.index < it.count4 {
+		return uint32ToIPV4(uint32(it.cur.Uint64()))
+	}
+	return uint128ToIPV6(it.cur)
+}
@@ -0,0 +1,179 @@
+// go test -v -run="TestIter"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIter(t *testing.T) {
+	type TestCase struct {
+		Input   []string
+		Options IterOptions
+		Output  []string
+		Error   bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:   nil,
+			Options: IterOptions{},
+			Output:  nil,
+			Error:   false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/33",
+			},
+			Options: IterOptions{},
+			Output:  nil,
+			Error:   true,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/30",
+				"192.0.2.1/32",
+			},
+			Options: IterOptions{},
+			Output: []string{
+				"192.0.2.0",
+				"192.0.2.1",
+				"192.0.2.2",
+				"192.0.2.3",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/30",
+				"198.51.100.0/31",
+				"203.0.113.7/32",
+			},
+			Options: IterOptions{HostsOnly: true},
+			Output: []string{
+				"192.0.2.1",
+				"192.0.2.2",
+				"198.51.100.0",
+				"198.51.100.1",
+				"203.0.113.7",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/29",
+			},
+			Options: IterOptions{Stride: 3},
+			Output: []string{
+				"192.0.2.0",
+				"192.0.2.3",
+				"192.0.2.6",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/30",
+				"192.0.2.8/30",
+			},
+			Options: IterOptions{Stride: 3},
+			Output: []string{
+				"192.0.2.0",
+				"192.0.2.3",
+				"192.0.2.10",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/30",
+				"192.0.2.8/30",
+			},
+			Options: IterOptions{Stride: 3, Reverse: true},
+			Output: []string{
+				"192.0.2.11",
+				"192.0.2.8",
+				"192.0.2.1",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"255.255.255.254/31",
+			},
+			Options: IterOptions{},
+			Output: []string{
+				"255.255.255.254",
+				"255.255.255.255",
+			},
+			Error: false,
+		},
+		// IPv6 tests
+		{
+			Input: []string{
+				"::/0",
+			},
+			Options: IterOptions{Stride: 1 << 63},
+			Output: []string{
+				"::",
+				"::8000:0:0:0",
+				"0:0:0:1::",
+				"::1:8000:0:0:0",
+				"0:0:0:2::",
+				"::2:8000:0:0:0",
+				"0:0:0:3::",
+				"::3:8000:0:0:0",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"2001:db8::/127",
+			},
+			Options: IterOptions{HostsOnly: true, Reverse: true},
+			Output: []string{
+				"2001:db8::1",
+				"2001:db8::",
+			},
+			Error: false,
+		},
+		// Mixed IPv4 and IPv6 tests
+		{
+			Input: []string{
+				"2001:db8::/127",
+				"192.0.2.0/31",
+			},
+			Options: IterOptions{Reverse: true},
+			Output: []string{
+				"2001:db8::1",
+				"2001:db8::",
+				"192.0.2.1",
+				"192.0.2.0",
+			},
+			Error: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		it, err := NewIterCIDRs(testCase.Input, testCase.Options)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("NewIterCIDRs(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("NewIterCIDRs(%#v) expected error", testCase.Input)
+			continue
+		}
+		var output []string
+		// Limit the number of addresses, the IPv6 tests would run for a long time
+		for i := 0; i < 8 && it.Next(); i++ {
+			output = append(output, it.IP().String())
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("Iter(%#v, %+v) expected: %#v, got: %#v", testCase.Input, testCase.Options, testCase.Output, output)
+		}
+	}
+}
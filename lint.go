@@ -0,0 +1,169 @@
+package cidrman
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// LintKind is the kind of issue reported by Lint.
+type LintKind int
+
+const (
+	// LintInvalid is an entry that is not a valid CIDR block.
+	LintInvalid LintKind = iota
+	// LintHostBits is an entry with host bits set, like 10.0.0.5/24.
+	LintHostBits
+	// LintDuplicate is an entry that is the same network as an earlier entry.
+	LintDuplicate
+	// LintContained is an entry inside the network of another entry.
+	LintContained
+	// LintAdjacent is an entry that forms a larger CIDR block together with another entry.
+	LintAdjacent
+	// LintMixedFamilies is the first entry of the second address family in a list with both IPv4 and IPv6.
+	LintMixedFamilies
+)
+
+// String returns the name of the kind.
+func (k LintKind) String() string {
+	switch k {
+	case LintInvalid:
+		return "invalid"
+	case LintHostBits:
+		return "host-bits"
+	case LintDuplicate:
+		return "duplicate"
+	case LintContained:
+		return "contained"
+	case LintAdjacent:
+		return "adjacent"
+	case LintMixedFamilies:
+		return "mixed-families"
+	}
+	return fmt.Sprintf("LintKind(%d)", int(k))
+}
+
+// LintFinding is an issue with an entry of a list of CIDR blocks.
+type LintFinding struct {
+	Kind LintKind
+	// Index is the index of the entry in the input.
+	Index int
+	// Other is the index of the related entry, like the entry containing this entry, or -1.
+	Other   int
+	Message string
+}
+
+// String returns the finding as "index: kind: message".
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%d: %s: %s", f.Index, f.Kind, f.Message)
+}
+
+// Lint checks a list of mixed CIDR blocks and returns the findings ordered by input index.
+// The entries are checked for invalid CIDR blocks, host bits set, exact duplicates, entries
+// contained in other entries, adjacent entries that could be merged and mixed address families.
+// Example:
+//     for _, finding := range Lint(cidrs) {
+//         fmt.Println(finding)  // 3: host-bits: 10.0.0.5/24 has host bits set, network 10.0.0.0/24
+//     }
+func Lint(cidrs []string) []LintFinding {
+	var findings []LintFinding
+
+	var networks []*net.IPNet
+	var indexes []int
+	for i, cidr := range cidrs {
+		ip, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			findings = append(findings, LintFinding{LintInvalid, i, -1, err.Error()})
+			continue
+		}
+		if !ip.Equal(network.IP) {
+			findings = append(findings, LintFinding{LintHostBits, i, -1,
+				fmt.Sprintf("%s has host bits set, network %s", cidr, network)})
+		}
+		networks = append(networks, network)
+		indexes = append(indexes, i)
+	}
+
+	// Sort by address family, address and prefix length, containing networks first
+	order, _ := sortedIndexes(networks, SortByAddress)
+	keys := make([]sortKey, len(networks))
+	for i, network := range networks {
+		keys[i], _ = newSortKey(network)
+	}
+
+	// The merge sweep of coalesce4 and coalesce6 groups the networks into coalesced blocks, only
+	// networks of the same block can be duplicates, contained or adjacent
+	block4s, block6s, _ := Options{}.splitFamilies(networks)
+	group4s := coalesce4(block4s)
+	group6s := coalesce6(block6s)
+	group := func(key sortKey) int {
+		if key.width == widthUInt32 {
+			return sort.Search(len(group4s), func(g int) bool {
+				return uint64(group4s[g].last) >= key.addr.lo
+			})
+		}
+		addr := ipv6ToUInt128(uint128ToIP(key.addr, widthUInt128))
+		return len(group4s) + sort.Search(len(group6s), func(g int) bool {
+			return group6s[g].last.Cmp(addr) >= 0
+		})
+	}
+
+	// Sweep the sorted networks of each coalesced block, keeping the stack of networks containing
+	// the current network, outermost first
+	var stack []int
+	first := -1
+	current := -1
+	for n, i := range order {
+		key := keys[i]
+		if g := group(key); g != current {
+			stack, current = stack[:0], g
+		}
+		if n > 0 && keys[order[n-1]] == key {
+			findings = append(findings, LintFinding{LintDuplicate, indexes[i], indexes[first],
+				fmt.Sprintf("%s is a duplicate of entry %d, %s", cidrs[indexes[i]], indexes[first], cidrs[indexes[first]])})
+			continue
+		}
+		first = i
+
+		// Networks ending before this network, the last one is its previous sibling
+		previous := -1
+		for len(stack) > 0 && keys[stack[len(stack)-1]].last().less(key.addr) {
+			previous = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			top := stack[0]
+			findings = append(findings, LintFinding{LintContained, indexes[i], indexes[top],
+				fmt.Sprintf("%s is contained in entry %d, %s", cidrs[indexes[i]], indexes[top], cidrs[indexes[top]])})
+		}
+		// Siblings of the same prefix length form the CIDR block one bit shorter
+		if previous >= 0 && key.prefix == keys[previous].prefix && key.prefix > 0 && keys[previous].last().addOne() == key.addr &&
+			keys[previous].addr.or(hostmask128(key.width-uint(key.prefix)+1)) == key.last() {
+			findings = append(findings, LintFinding{LintAdjacent, indexes[i], indexes[previous],
+				fmt.Sprintf("%s can be merged with entry %d, %s", cidrs[indexes[i]], indexes[previous], cidrs[indexes[previous]])})
+		}
+		stack = append(stack, i)
+	}
+
+	// Mixed address families, reported at the first entry of the second family
+	for i := range keys {
+		if keys[i].width != keys[0].width {
+			findings = append(findings, LintFinding{LintMixedFamilies, indexes[i], indexes[0],
+				fmt.Sprintf("%s is %s and entry %d, %s, is %s", cidrs[indexes[i]], familyName(keys[i].width), indexes[0], cidrs[indexes[0]], familyName(keys[0].width))})
+			break
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Index < findings[j].Index
+	})
+	return findings
+}
+
+// familyName returns the name of the address family of the width.
+func familyName(width uint) string {
+	if width == widthUInt32 {
+		return "IPv4"
+	}
+	return "IPv6"
+}
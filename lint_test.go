@@ -0,0 +1,125 @@
+// go test -v -run="TestLint"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Output []string
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/8",
+				"192.0.2.0/24",
+			},
+			Output: nil,
+		},
+		{
+			Input: []string{
+				"10.0.0.5/24",
+				"10.0.0.0/24",
+				"10.0.0.0/33",
+				"10.0.1.0/24",
+				"10.0.0.0/24",
+				"10.0.0.128/25",
+				"10.0.0.128/25",
+				"10.0.2.0/24",
+			},
+			Output: []string{
+				"0: host-bits: 10.0.0.5/24 has host bits set, network 10.0.0.0/24",
+				"1: duplicate: 10.0.0.0/24 is a duplicate of entry 0, 10.0.0.5/24",
+				"2: invalid: invalid CIDR address: 10.0.0.0/33",
+				"3: adjacent: 10.0.1.0/24 can be merged with entry 0, 10.0.0.5/24",
+				"4: duplicate: 10.0.0.0/24 is a duplicate of entry 0, 10.0.0.5/24",
+				"5: contained: 10.0.0.128/25 is contained in entry 0, 10.0.0.5/24",
+				"6: duplicate: 10.0.0.128/25 is a duplicate of entry 5, 10.0.0.128/25",
+			},
+		},
+		{
+			// Adjacent, but not forming a larger CIDR block
+			Input: []string{
+				"10.0.1.0/24",
+				"10.0.2.0/24",
+				"10.0.3.0/25",
+			},
+			Output: nil,
+		},
+		{
+			Input: []string{
+				"2001:db8::/32",
+				"2001:db9::/32",
+				"2001:db8:1::/48",
+				"0.0.0.0/0",
+				"::/0",
+				"2001:db8::1/32",
+			},
+			Output: []string{
+				"0: contained: 2001:db8::/32 is contained in entry 4, ::/0",
+				"1: contained: 2001:db9::/32 is contained in entry 4, ::/0",
+				"1: adjacent: 2001:db9::/32 can be merged with entry 0, 2001:db8::/32",
+				"2: contained: 2001:db8:1::/48 is contained in entry 4, ::/0",
+				"3: mixed-families: 0.0.0.0/0 is IPv4 and entry 0, 2001:db8::/32, is IPv6",
+				"5: host-bits: 2001:db8::1/32 has host bits set, network 2001:db8::/32",
+				"5: duplicate: 2001:db8::1/32 is a duplicate of entry 0, 2001:db8::/32",
+			},
+		},
+		{
+			// Adjacent under a covering entry
+			Input: []string{
+				"10.0.0.0/16",
+				"10.0.0.0/24",
+				"10.0.0.0/25",
+				"10.0.1.0/24",
+				"10.0.2.0/24",
+			},
+			Output: []string{
+				"1: contained: 10.0.0.0/24 is contained in entry 0, 10.0.0.0/16",
+				"2: contained: 10.0.0.0/25 is contained in entry 0, 10.0.0.0/16",
+				"3: contained: 10.0.1.0/24 is contained in entry 0, 10.0.0.0/16",
+				"3: adjacent: 10.0.1.0/24 can be merged with entry 1, 10.0.0.0/24",
+				"4: contained: 10.0.2.0/24 is contained in entry 0, 10.0.0.0/16",
+			},
+		},
+		{
+			// IPv4-mapped networks are IPv4 networks from prefix length 96
+			Input: []string{
+				"::ffff:10.0.0.0/104",
+				"192.0.2.0/24",
+				"10.1.0.0/16",
+			},
+			Output: []string{
+				"2: contained: 10.1.0.0/16 is contained in entry 0, ::ffff:10.0.0.0/104",
+			},
+		},
+		{
+			Input: []string{
+				"2001:db8::/33",
+				"2001:db8:8000::/33",
+			},
+			Output: []string{
+				"1: adjacent: 2001:db8:8000::/33 can be merged with entry 0, 2001:db8::/33",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		var output []string
+		for _, finding := range Lint(testCase.Input) {
+			output = append(output, finding.String())
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("Lint(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
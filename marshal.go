@@ -0,0 +1,196 @@
+package cidrman
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"net"
+	"strings"
+)
+
+// setEncodingVersion is the version of the binary set encoding.
+//
+// Version 1 layout, all integers are unsigned varints unless noted:
+//     version (1 byte)
+//     number of IPv4 intervals
+//     per IPv4 interval: gap to the previous interval, size of the interval - 1
+//     number of IPv6 intervals
+//     per IPv6 interval: gap and size - 1 as length-prefixed big-endian integers
+// The gap of the first interval is its first address, the gap of the following intervals
+// is the number of addresses between them minus 1, since merged intervals never touch.
+const setEncodingVersion = 1
+
+// MarshalText implements encoding.TextMarshaler, the set is encoded as comma-separated CIDR blocks.
+func (s *Set) MarshalText() ([]byte, error) {
+	return []byte(strings.Join(s.CIDRs(), ",")), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for comma-separated CIDR blocks.
+func (s *Set) UnmarshalText(text []byte) error {
+	var cidrs []string
+	for _, cidr := range strings.Split(string(text), ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	set, err := NewSetCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+	*s = *set
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the set is encoded as a list of CIDR blocks.
+func (s *Set) MarshalJSON() ([]byte, error) {
+	cidrs := s.CIDRs()
+	if cidrs == nil {
+		cidrs = make([]string, 0)
+	}
+	return json.Marshal(cidrs)
+}
+
+// UnmarshalJSON implements json.Unmarshaler for a list of CIDR blocks.
+func (s *Set) UnmarshalJSON(data []byte) error {
+	var cidrs []string
+	if err := json.Unmarshal(data, &cidrs); err != nil {
+		return err
+	}
+
+	set, err := NewSetCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+	*s = *set
+	return nil
+}
+
+// appendUvarint appends an unsigned varint.
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	return append(buf, tmp[:n]...)
+}
+
+// appendUInt128 appends a length-prefixed big-endian unsigned 128-bit integer.
+func appendUInt128(buf []byte, x *big.Int) []byte {
+	b := x.Bytes()
+	buf = append(buf, byte(len(b)))
+	return append(buf, b...)
+}
+
+// readUInt128 reads a length-prefixed big-endian unsigned 128-bit integer.
+func readUInt128(data []byte) (*big.Int, []byte, error) {
+	if len(data) < 1 || int(data[0]) > net.IPv6len || len(data) < 1+int(data[0]) {
+		return nil, nil, errors.New("Invalid set encoding: truncated IPv6 interval")
+	}
+	n := 1 + int(data[0])
+	return big.NewInt(0).SetBytes(data[1:n]), data[n:], nil
+}
+
+// readUvarint reads an unsigned varint.
+func readUvarint(data []byte) (uint64, []byte, error) {
+	x, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, errors.New("Invalid set encoding: truncated varint")
+	}
+	return x, data[n:], nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler with a compact encoding of the
+// delta-encoded sorted intervals of the set.
+func (s *Set) MarshalBinary() ([]byte, error) {
+	block4s, block6s := s.blocks()
+
+	buf := []byte{setEncodingVersion}
+	buf = appendUvarint(buf, uint64(len(block4s)))
+	next := uint64(0)
+	for _, block := range block4s {
+		buf = appendUvarint(buf, uint64(block.first)-next)
+		buf = appendUvarint(buf, uint64(block.last-block.first))
+		next = uint64(block.last) + 2
+	}
+
+	buf = appendUvarint(buf, uint64(len(block6s)))
+	next6 := big.NewInt(0)
+	for _, block := range block6s {
+		buf = appendUInt128(buf, big.NewInt(0).Sub(block.first, next6))
+		buf = appendUInt128(buf, big.NewInt(0).Sub(block.last, block.first))
+		next6.Add(block.last, big.NewInt(2))
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler for the encoding of MarshalBinary.
+func (s *Set) UnmarshalBinary(data []byte) error {
+	if len(data) < 1 {
+		return errors.New("Invalid set encoding: empty")
+	}
+	if data[0] != setEncodingVersion {
+		return fmt.Errorf("Unsupported set encoding version: %d", data[0])
+	}
+	data = data[1:]
+
+	var nets []*net.IPNet
+	count, data, err := readUvarint(data)
+	if err != nil {
+		return err
+	}
+	next := uint64(0)
+	for ; count > 0; count-- {
+		var gap, size uint64
+		if gap, data, err = readUvarint(data); err != nil {
+			return err
+		}
+		if size, data, err = readUvarint(data); err != nil {
+			return err
+		}
+		first := next + gap
+		last := first + size
+		if first < next || last < first || last > maxUInt32 {
+			return errors.New("Invalid set encoding: IPv4 interval out of range")
+		}
+		// Start the split at the common prefix, skipping the levels above it
+		prefix := uint(bits.LeadingZeros32(uint32(first ^ last)))
+		if err := splitRange4(network4(uint32(first), prefix), prefix, uint32(first), uint32(last), &nets); err != nil {
+			return err
+		}
+		next = last + 2
+	}
+
+	if count, data, err = readUvarint(data); err != nil {
+		return err
+	}
+	next6 := big.NewInt(0)
+	for ; count > 0; count-- {
+		var gap, size *big.Int
+		if gap, data, err = readUInt128(data); err != nil {
+			return err
+		}
+		if size, data, err = readUInt128(data); err != nil {
+			return err
+		}
+		first := big.NewInt(0).Add(next6, gap)
+		last := big.NewInt(0).Add(first, size)
+		if last.Cmp(maxUInt128) > 0 {
+			return errors.New("Invalid set encoding: IPv6 interval out of range")
+		}
+		prefix := uint(widthUInt128 - big.NewInt(0).Xor(first, last).BitLen())
+		if err := splitRange6(network6(first, prefix), prefix, first, last, &nets); err != nil {
+			return err
+		}
+		next6.Add(last, big.NewInt(2))
+	}
+
+	if len(data) != 0 {
+		return errors.New("Invalid set encoding: trailing data")
+	}
+
+	s.nets = nets
+	return nil
+}
@@ -0,0 +1,162 @@
+// go test -v -run="TestSetMarshal"
+// go test -run=NONE -bench="BenchmarkSet"
+
+package cidrman
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSetMarshal(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Text   string
+		JSON   string
+		Binary []byte
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Text:   "",
+			JSON:   `[]`,
+			Binary: []byte{1, 0, 0},
+		},
+		{
+			Input: []string{
+				"10.0.0.0/8",
+			},
+			Text:   "10.0.0.0/8",
+			JSON:   `["10.0.0.0/8"]`,
+			Binary: []byte{1, 1, 0x80, 0x80, 0x80, 0x50, 0xff, 0xff, 0xff, 0x07, 0},
+		},
+		{
+			Input: []string{
+				"192.0.2.0/24",
+				"192.0.3.0/25",
+				"192.0.2.0/25",
+				"192.0.3.132/32",
+			},
+			Text:   "192.0.2.0/24,192.0.3.0/25,192.0.3.132/32",
+			JSON:   `["192.0.2.0/24","192.0.3.0/25","192.0.3.132/32"]`,
+			Binary: []byte{1, 2, 0x80, 0x84, 0x80, 0x80, 0x0c, 0xff, 0x02, 0x03, 0, 0},
+		},
+		{
+			Input: []string{
+				"0.0.0.0/0",
+				"::/0",
+			},
+			Text:   "0.0.0.0/0,::/0",
+			JSON:   `["0.0.0.0/0","::/0"]`,
+			Binary: []byte{1, 1, 0, 0xff, 0xff, 0xff, 0xff, 0x0f, 1, 0, 16, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			Input: []string{
+				"2001:db8::/64",
+				"2001:db8:0:1::/64",
+				"2001:db8:0:2::1/128",
+			},
+			Text:   "2001:db8::/63,2001:db8:0:2::1/128",
+			JSON:   `["2001:db8::/63","2001:db8:0:2::1/128"]`,
+			Binary: []byte{1, 0, 2, 16, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 9, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0},
+		},
+	}
+
+	for _, testCase := range testCases {
+		set, err := NewSetCIDRs(testCase.Input)
+		if err != nil {
+			t.Errorf("NewSetCIDRs(%#v) failed: %s", testCase.Input, err.Error())
+			continue
+		}
+		merged, _ := MergeCIDRs(testCase.Input)
+
+		text, _ := set.MarshalText()
+		if string(text) != testCase.Text {
+			t.Errorf("MarshalText(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Text, string(text))
+		}
+		var fromText Set
+		if err := fromText.UnmarshalText(text); err != nil || !reflect.DeepEqual(merged, fromText.CIDRs()) {
+			t.Errorf("UnmarshalText(%#v) expected: %#v, got: %#v (%v)", string(text), merged, fromText.CIDRs(), err)
+		}
+
+		data, _ := json.Marshal(set)
+		if string(data) != testCase.JSON {
+			t.Errorf("MarshalJSON(%#v) expected: %#v, got: %#v", testCase.Input, testCase.JSON, string(data))
+		}
+		var fromJSON Set
+		if err := json.Unmarshal(data, &fromJSON); err != nil || !reflect.DeepEqual(merged, fromJSON.CIDRs()) {
+			t.Errorf("UnmarshalJSON(%#v) expected: %#v, got: %#v (%v)", string(data), merged, fromJSON.CIDRs(), err)
+		}
+
+		binary, _ := set.MarshalBinary()
+		if !reflect.DeepEqual(binary, testCase.Binary) {
+			t.Errorf("MarshalBinary(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Binary, binary)
+		}
+		var fromBinary Set
+		if err := fromBinary.UnmarshalBinary(binary); err != nil || !reflect.DeepEqual(merged, fromBinary.CIDRs()) {
+			t.Errorf("UnmarshalBinary(%#v) expected: %#v, got: %#v (%v)", binary, merged, fromBinary.CIDRs(), err)
+		}
+	}
+}
+
+func TestSetUnmarshalBinaryErrors(t *testing.T) {
+	testCases := [][]byte{
+		// Empty
+		{},
+		// Unknown version
+		{2, 0, 0},
+		// Truncated
+		{1, 1, 0x80},
+		{1, 0, 1, 17},
+		// IPv4 interval beyond 255.255.255.255
+		{1, 1, 0x80, 0x80, 0x80, 0x80, 0x10, 0},
+		// Second IPv4 interval overflows
+		{1, 2, 0, 0xfe, 0xff, 0xff, 0xff, 0x0f, 0, 1, 0},
+		// IPv6 interval beyond ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff
+		{1, 0, 1, 1, 1, 16, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		// Trailing data
+		{1, 0, 0, 0},
+	}
+
+	for _, testCase := range testCases {
+		var set Set
+		if err := set.UnmarshalBinary(testCase); err == nil {
+			t.Errorf("UnmarshalBinary(%#v) expected error, got: %#v", testCase, set.CIDRs())
+		}
+	}
+}
+
+// benchmarkCIDRs returns a list of n non-adjacent /24 CIDR blocks.
+func benchmarkCIDRs(n int) []string {
+	cidrs := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		addr := uint32(i) << 9
+		cidrs = append(cidrs, fmt.Sprintf("%d.%d.%d.0/24", byte(addr>>24), byte(addr>>16), byte(addr>>8)))
+	}
+	return cidrs
+}
+
+func BenchmarkSetNewSetCIDRs(b *testing.B) {
+	cidrs := benchmarkCIDRs(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewSetCIDRs(cidrs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetUnmarshalBinary(b *testing.B) {
+	set, _ := NewSetCIDRs(benchmarkCIDRs(10000))
+	data, _ := set.MarshalBinary()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var s Set
+		if err := s.UnmarshalBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Conversion between CIDR notation and the address/mask notations used by
+// Cisco ACLs (wildcard masks) and legacy systems (dotted netmasks).
+
+package cidrman
+
+import (
+	"fmt"
+	"math/bits"
+	"net"
+	"strings"
+)
+
+// maxWildcardBits is the largest number of non-contiguous wildcard bits that will be expanded,
+// a non-contiguous wildcard mask expands to 2^bits CIDR blocks.
+const maxWildcardBits = 16
+
+// parseAddrMask splits an "address mask" string and parses both parts as IP addresses.
+func parseAddrMask(s string) (net.IP, net.IP, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, nil, fmt.Errorf("Invalid address and mask: %s", s)
+	}
+	ip := net.ParseIP(fields[0])
+	if ip == nil {
+		return nil, nil, fmt.Errorf("Invalid IP address: %s", fields[0])
+	}
+	mask := net.ParseIP(fields[1])
+	if mask == nil {
+		return nil, nil, fmt.Errorf("Invalid mask: %s", fields[1])
+	}
+	return ip, mask, nil
+}
+
+// ParseNetmask parses a dotted netmask notation string like "10.0.0.0 255.255.0.0".
+// Like net.ParseCIDR, any host bits in the address are masked off.
+func ParseNetmask(s string) (*net.IPNet, error) {
+	ip, mask, err := parseAddrMask(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var ipMask net.IPMask
+	if ip4 := ip.To4(); ip4 != nil {
+		mask4 := mask.To4()
+		if mask4 == nil {
+			return nil, fmt.Errorf("Mismatched IP address types: %s", s)
+		}
+		ip, ipMask = ip4, net.IPMask(mask4)
+	} else {
+		if mask.To4() != nil {
+			return nil, fmt.Errorf("Mismatched IP address types: %s", s)
+		}
+		ipMask = net.IPMask(mask.To16())
+	}
+	if ones, size := ipMask.Size(); ones == 0 && size == 0 {
+		return nil, fmt.Errorf("Non-contiguous netmask: %s", s)
+	}
+
+	return &net.IPNet{IP: ip.Mask(ipMask), Mask: ipMask}, nil
+}
+
+// ParseWildcard parses a wildcard mask notation string like "10.0.0.0 0.0.255.255".
+// A wildcard mask is an inverted netmask where 1-bits are "don't care" bits. Only IPv4 is supported.
+// A non-contiguous wildcard mask like "10.0.0.1 0.0.255.0" is expanded into its exact set of CIDR blocks.
+func ParseWildcard(s string) ([]*net.IPNet, error) {
+	ip, mask, err := parseAddrMask(s)
+	if err != nil {
+		return nil, err
+	}
+	ip4 := ip.To4()
+	mask4 := mask.To4()
+	if ip4 == nil || mask4 == nil {
+		return nil, fmt.Errorf("Wildcard masks are only supported for IPv4: %s", s)
+	}
+
+	wildcard := ipv4ToUInt32(mask4)
+	// Trailing 1-bits form the host part of every expanded block
+	hostBits := uint(bits.TrailingZeros32(^wildcard))
+	free := wildcard &^ hostmask4(widthUInt32-hostBits)
+	if bits.OnesCount32(free) > maxWildcardBits {
+		return nil, fmt.Errorf("Too many non-contiguous wildcard bits: %s", s)
+	}
+
+	base := ipv4ToUInt32(ip4) &^ wildcard
+	cidrMask := net.CIDRMask(int(widthUInt32-hostBits), 8*net.IPv4len)
+	var nets []*net.IPNet
+	// Enumerate all subsets of the free bits, ending with the empty subset
+	for sub := free; ; sub = (sub - 1) & free {
+		nets = append(nets, &net.IPNet{IP: uint32ToIPV4(base | sub), Mask: cidrMask})
+		if sub == 0 {
+			break
+		}
+	}
+
+	return MergeIPNets(nets)
+}
+
+// NetmaskString returns the dotted netmask notation of an IP network, e.g. "10.0.0.0 255.255.0.0".
+func NetmaskString(n *net.IPNet) string {
+	return fmt.Sprintf("%s %s", n.IP, net.IP(n.Mask))
+}
+
+// WildcardString returns the wildcard mask notation of an IPv4 network, e.g. "10.0.0.0 0.0.255.255".
+func WildcardString(n *net.IPNet) string {
+	wildcard := make(net.IP, len(n.Mask))
+	for i, b := range n.Mask {
+		wildcard[i] = ^b
+	}
+	return fmt.Sprintf("%s %s", n.IP, wildcard)
+}
+
+// NetmasksToCIDRs accepts a list of dotted netmask notation strings and merges them into the smallest possible list of CIDRs.
+func NetmasksToCIDRs(netmasks []string) ([]string, error) {
+	if netmasks == nil {
+		return nil, nil
+	}
+	if len(netmasks) == 0 {
+		return make([]string, 0), nil
+	}
+
+	var networks []*net.IPNet
+	for _, netmask := range netmasks {
+		network, err := ParseNetmask(netmask)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	mergedNets, err := MergeIPNets(networks)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipNets(mergedNets).toCIDRs(), nil
+}
+
+// WildcardsToCIDRs accepts a list of wildcard mask notation strings and merges them into the smallest possible list of CIDRs.
+func WildcardsToCIDRs(wildcards []string) ([]string, error) {
+	if wildcards == nil {
+		return nil, nil
+	}
+	if len(wildcards) == 0 {
+		return make([]string, 0), nil
+	}
+
+	var networks []*net.IPNet
+	for _, wildcard := range wildcards {
+		nets, err := ParseWildcard(wildcard)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, nets...)
+	}
+	mergedNets, err := MergeIPNets(networks)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipNets(mergedNets).toCIDRs(), nil
+}
+
+// CIDRsToNetmasks accepts a list of CIDR blocks and returns them in dotted netmask notation.
+func CIDRsToNetmasks(cidrs []string) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+
+	netmasks := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		netmasks = append(netmasks, NetmaskString(network))
+	}
+	return netmasks, nil
+}
+
+// CIDRsToWildcards accepts a list of IPv4 CIDR blocks and returns them in wildcard mask notation.
+func CIDRsToWildcards(cidrs []string) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+
+	wildcards := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		if network.IP.To4() == nil {
+			return nil, fmt.Errorf("Wildcard masks are only supported for IPv4: %s", cidr)
+		}
+		wildcards = append(wildcards, WildcardString(network))
+	}
+	return wildcards, nil
+}
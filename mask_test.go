@@ -0,0 +1,278 @@
+// go test -v -run="TestWildcardsToCIDRs|TestNetmasksToCIDRs|TestCIDRsToWildcards"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWildcardsToCIDRs(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input:  []string{},
+			Output: []string{},
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0",
+			},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"2001:db8:: ::ffff",
+			},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"10.0.0.0 0.255.255.254",
+			},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"10.0.0.0 0.0.255.255",
+			},
+			Output: []string{
+				"10.0.0.0/16",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"0.0.0.0 255.255.255.255",
+			},
+			Output: []string{
+				"0.0.0.0/0",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"192.0.2.1 0.0.0.0",
+			},
+			Output: []string{
+				"192.0.2.1/32",
+			},
+			Error: false,
+		},
+		{
+			// Don't care bits in the address are ignored
+			Input: []string{
+				"10.0.1.2 0.0.255.255",
+			},
+			Output: []string{
+				"10.0.0.0/16",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"10.0.0.1 0.0.3.0",
+			},
+			Output: []string{
+				"10.0.0.1/32",
+				"10.0.1.1/32",
+				"10.0.2.1/32",
+				"10.0.3.1/32",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0 0.0.2.255",
+			},
+			Output: []string{
+				"10.0.0.0/24",
+				"10.0.2.0/24",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0 0.0.2.255",
+				"10.0.1.0 0.0.2.255",
+			},
+			Output: []string{
+				"10.0.0.0/22",
+			},
+			Error: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := WildcardsToCIDRs(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("WildcardsToCIDRs(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("WildcardsToCIDRs(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("WildcardsToCIDRs(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
+
+func TestNetmasksToCIDRs(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0 255.0.255.0",
+			},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"10.0.0.0 ffff::",
+			},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"10.0.0.0 255.255.0.0",
+			},
+			Output: []string{
+				"10.0.0.0/16",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"192.0.2.77 255.255.255.0",
+				"192.0.3.0 255.255.255.0",
+			},
+			Output: []string{
+				"192.0.2.0/23",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"2001:db8:: ffff:ffff::",
+			},
+			Output: []string{
+				"2001:db8::/32",
+			},
+			Error: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := NetmasksToCIDRs(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("NetmasksToCIDRs(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("NetmasksToCIDRs(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("NetmasksToCIDRs(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
+
+func TestCIDRsToWildcards(t *testing.T) {
+	type TestCase struct {
+		Input     []string
+		Wildcards []string
+		Netmasks  []string
+		Error     bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:     nil,
+			Wildcards: nil,
+			Netmasks:  nil,
+			Error:     false,
+		},
+		{
+			Input: []string{
+				"2001:db8::/32",
+			},
+			Error: true,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/16",
+				"192.0.2.1/32",
+				"0.0.0.0/0",
+			},
+			Wildcards: []string{
+				"10.0.0.0 0.0.255.255",
+				"192.0.2.1 0.0.0.0",
+				"0.0.0.0 255.255.255.255",
+			},
+			Netmasks: []string{
+				"10.0.0.0 255.255.0.0",
+				"192.0.2.1 255.255.255.255",
+				"0.0.0.0 0.0.0.0",
+			},
+			Error: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		wildcards, err := CIDRsToWildcards(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("CIDRsToWildcards(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("CIDRsToWildcards(%#v) expected error, got: %#v", testCase.Input, wildcards)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Wildcards, wildcards) {
+			t.Errorf("CIDRsToWildcards(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Wildcards, wildcards)
+		}
+		netmasks, err := CIDRsToNetmasks(testCase.Input)
+		if err != nil {
+			t.Errorf("CIDRsToNetmasks(%#v) failed: %s", testCase.Input, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Netmasks, netmasks) {
+			t.Errorf("CIDRsToNetmasks(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Netmasks, netmasks)
+		}
+	}
+}
@@ -20,6 +20,23 @@ func (nets ipNets) toCIDRs() []string {
 // MergeIPNets accepts a list of IP networks and merges them into the smallest possible list of IPNets.
 // It merges adjacent subnets where possible, those contained within others and removes any duplicates.
 func MergeIPNets(nets []*net.IPNet) ([]*net.IPNet, error) {
+	return Merge(nets)
+}
+
+// Merge is MergeIPNets with options.
+// Example:
+//     merged, err := Merge(nets, WithFamily(FamilyIPv4), WithIPv4Mapped(IPv4MappedReject))
+func Merge(nets []*net.IPNet, opts ...Option) ([]*net.IPNet, error) {
+	o := newOptions(opts)
+	merged, err := merge(nets, o)
+	if err != nil {
+		return nil, err
+	}
+	return o.sorted(merged)
+}
+
+// merge merges a list of IP networks with options, IPv4 before IPv6, each by address.
+func merge(nets []*net.IPNet, o Options) ([]*net.IPNet, error) {
 	if nets == nil {
 		return nil, nil
 	}
@@ -29,20 +46,12 @@ func MergeIPNets(nets []*net.IPNet) ([]*net.IPNet, error) {
 
 	// Split into IPv4 and IPv6 lists.
 	// Handle the lists separately and then combine.
-	var block4s cidrBlock4s
-	var block6s cidrBlock6s
-	for _, net := range nets {
-		ip4 := net.IP.To4()
-		if ip4 != nil {
-			block4s = append(block4s, newBlock4(ip4, net.Mask))
-		} else {
-			ip6 := net.IP.To16()
-			block6s = append(block6s, newBlock6(ip6, net.Mask))
-		}
+	block4s, block6s, err := o.splitFamilies(nets)
+	if err != nil {
+		return nil, err
 	}
 
 	var merged4 []*net.IPNet
-	var err error
 	if len(block4s) > 0 {
 		merged4, err = merge4(block4s)
 		if err != nil {
@@ -59,30 +68,14 @@ func MergeIPNets(nets []*net.IPNet) ([]*net.IPNet, error) {
 	}
 
 	merged := append(merged4, merged6...)
+	if merged == nil {
+		// All networks were filtered out
+		return make([]*net.IPNet, 0), nil
+	}
 	return merged, nil
 }
 
 // MergeCIDRs accepts a list of CIDR blocks and merges them into the smallest possible list of CIDRs.
 func MergeCIDRs(cidrs []string) ([]string, error) {
-	if cidrs == nil {
-		return nil, nil
-	}
-	if len(cidrs) == 0 {
-		return make([]string, 0), nil
-	}
-
-	var networks []*net.IPNet
-	for _, cidr := range cidrs {
-		_, network, err := net.ParseCIDR(cidr)
-		if err != nil {
-			return nil, err
-		}
-		networks = append(networks, network)
-	}
-	mergedNets, err := MergeIPNets(networks)
-	if err != nil {
-		return nil, err
-	}
-
-	return ipNets(mergedNets).toCIDRs(), nil
+	return MergeCIDRsWithOptions(cidrs, Options{})
 }
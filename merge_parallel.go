@@ -0,0 +1,225 @@
+// Parallel merging of very large lists of networks.
+// The address space is partitioned by the first byte of the addresses, the partitions are
+// coalesced concurrently and blocks that cross partition boundaries are stitched together
+// before the final split into CIDR blocks, which is also done concurrently.
+
+package cidrman
+
+import (
+	"math/big"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelPartitions is the number of partitions of each address family, one per first address byte.
+const parallelPartitions = 256
+
+// parallelMinNets is the smallest input handled in parallel, smaller inputs use the sequential merge.
+const parallelMinNets = 1 << 14
+
+// parallelFor calls fn for each index in [0, n) from at most workers goroutines.
+func parallelFor(n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// chunk returns the bounds of chunk i when n items are divided into count chunks.
+func chunk(n, count, i int) (int, int) {
+	return n * i / count, n * (i + 1) / count
+}
+
+// stitch4 concatenates the coalesced blocks of consecutive IPv4 partitions and coalesces
+// blocks that overlap or touch across partition boundaries.
+func stitch4(parts []cidrBlock4s) cidrBlock4s {
+	var stitched cidrBlock4s
+	for _, part := range parts {
+		for _, block := range part {
+			if n := len(stitched); n > 0 && (stitched[n-1].last == maxUInt32 || block.first <= stitched[n-1].last+1) {
+				if block.last > stitched[n-1].last {
+					stitched[n-1].last = block.last
+				}
+				continue
+			}
+			stitched = append(stitched, block)
+		}
+	}
+	return stitched
+}
+
+// stitch6 concatenates the coalesced blocks of consecutive IPv6 partitions and coalesces
+// blocks that overlap or touch across partition boundaries.
+func stitch6(parts []cidrBlock6s) cidrBlock6s {
+	var stitched cidrBlock6s
+	cmp := big.NewInt(0)
+	for _, part := range parts {
+		for _, block := range part {
+			if n := len(stitched); n > 0 {
+				cmp.Add(stitched[n-1].last, big.NewInt(1))
+				if block.first.Cmp(cmp) <= 0 {
+					if block.last.Cmp(stitched[n-1].last) > 0 {
+						stitched[n-1].last = block.last
+					}
+					continue
+				}
+			}
+			stitched = append(stitched, block)
+		}
+	}
+	return stitched
+}
+
+// mergeParallel merges a list of networks like MergeIPNets, using the given number of workers.
+func mergeParallel(nets []*net.IPNet, workers int) ([]*net.IPNet, error) {
+	// Convert the networks to blocks, each worker sorts its chunk of the input into partitions
+	parts4 := make([][]cidrBlock4s, workers)
+	parts6 := make([][]cidrBlock6s, workers)
+	parallelFor(workers, workers, func(w int) {
+		lo, hi := chunk(len(nets), workers, w)
+		slab := make([]cidrBlock4, 0, hi-lo)
+		parts4[w] = make([]cidrBlock4s, parallelPartitions)
+		parts6[w] = make([]cidrBlock6s, parallelPartitions)
+		for _, net := range nets[lo:hi] {
+			// IPv4-mapped networks like MergeIPNets
+			ip4, mask, _ := ipv4Network(net)
+			if ip4 != nil {
+				slab = append(slab, *newBlock4(ip4, mask))
+				parts4[w][ip4[0]] = append(parts4[w][ip4[0]], &slab[len(slab)-1])
+			} else {
+				ip6 := net.IP.To16()
+				parts6[w][ip6[0]] = append(parts6[w][ip6[0]], newBlock6(ip6, net.Mask))
+			}
+		}
+	})
+
+	// Coalesce each partition
+	coalesced4 := make([]cidrBlock4s, parallelPartitions)
+	coalesced6 := make([]cidrBlock6s, parallelPartitions)
+	parallelFor(2*parallelPartitions, workers, func(i int) {
+		p := i % parallelPartitions
+		n := 0
+		if i < parallelPartitions {
+			for w := range parts4 {
+				n += len(parts4[w][p])
+			}
+			blocks := make(cidrBlock4s, 0, n)
+			for w := range parts4 {
+				blocks = append(blocks, parts4[w][p]...)
+			}
+			coalesced4[p] = coalesce4(blocks)
+		} else {
+			for w := range parts6 {
+				n += len(parts6[w][p])
+			}
+			blocks := make(cidrBlock6s, 0, n)
+			for w := range parts6 {
+				blocks = append(blocks, parts6[w][p]...)
+			}
+			coalesced6[p] = coalesce6(blocks)
+		}
+	})
+
+	// Stitch the partitions and split the blocks into CIDR blocks, in chunks of blocks
+	block4s := stitch4(coalesced4)
+	block6s := stitch6(coalesced6)
+	split4 := make([][]*net.IPNet, workers)
+	split6 := make([][]*net.IPNet, workers)
+	errs := make([]error, 2*workers)
+	parallelFor(2*workers, workers, func(i int) {
+		w := i % workers
+		if i < workers {
+			lo, hi := chunk(len(block4s), workers, w)
+			for _, block := range block4s[lo:hi] {
+				if errs[i] = splitRange4(0, 0, block.first, block.last, &split4[w]); errs[i] != nil {
+					return
+				}
+			}
+		} else {
+			lo, hi := chunk(len(block6s), workers, w)
+			for _, block := range block6s[lo:hi] {
+				if errs[i] = splitRange6(big.NewInt(0), 0, block.first, block.last, &split6[w]); errs[i] != nil {
+					return
+				}
+			}
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var merged []*net.IPNet
+	for _, nets := range append(split4, split6...) {
+		merged = append(merged, nets...)
+	}
+	return merged, nil
+}
+
+// MergeIPNetsParallel merges a list of IP networks like MergeIPNets, using up to workers goroutines.
+// A workers value of 0 or less uses runtime.GOMAXPROCS(0) goroutines.
+// The output is identical to MergeIPNets, small inputs are merged sequentially.
+func MergeIPNetsParallel(nets []*net.IPNet, workers int) ([]*net.IPNet, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers == 1 || len(nets) < parallelMinNets {
+		return MergeIPNets(nets)
+	}
+
+	return mergeParallel(nets, workers)
+}
+
+// MergeCIDRsParallel merges a list of CIDR blocks like MergeCIDRs, using up to workers goroutines.
+// The CIDR blocks are also parsed concurrently.
+func MergeCIDRsParallel(cidrs []string, workers int) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+	if len(cidrs) == 0 {
+		return make([]string, 0), nil
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	networks := make([]*net.IPNet, len(cidrs))
+	errs := make([]error, workers)
+	parallelFor(workers, workers, func(w int) {
+		lo, hi := chunk(len(cidrs), workers, w)
+		for i := lo; i < hi; i++ {
+			if _, networks[i], errs[w] = net.ParseCIDR(cidrs[i]); errs[w] != nil {
+				return
+			}
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	mergedNets, err := MergeIPNetsParallel(networks, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipNets(mergedNets).toCIDRs(), nil
+}
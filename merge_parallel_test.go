@@ -0,0 +1,156 @@
+// go test -v -run="TestMergeIPNetsParallel"
+// go test -run=NONE -bench="BenchmarkMerge" -benchmem -timeout=30m
+
+package cidrman
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// randomIPNets returns a reproducible list of n networks with IPv4 prefix lengths from
+// minPrefix to 32, and every eighth network an IPv6 network with prefix length from minPrefix to 128.
+func randomIPNets(n int, minPrefix int, seed int64) []*net.IPNet {
+	r := rand.New(rand.NewSource(seed))
+	nets := make([]*net.IPNet, 0, n)
+	for i := 0; i < n; i++ {
+		if i%8 == 7 {
+			ip := make(net.IP, net.IPv6len)
+			r.Read(ip)
+			mask := net.CIDRMask(minPrefix+r.Intn(129-minPrefix), 8*net.IPv6len)
+			nets = append(nets, &net.IPNet{IP: ip.Mask(mask), Mask: mask})
+			continue
+		}
+		ip := uint32ToIPV4(r.Uint32())
+		mask := net.CIDRMask(minPrefix+r.Intn(33-minPrefix), 8*net.IPv4len)
+		nets = append(nets, &net.IPNet{IP: ip.Mask(mask), Mask: mask})
+	}
+	return nets
+}
+
+func TestMergeIPNetsParallel(t *testing.T) {
+	type TestCase struct {
+		Input   []string
+		Workers int
+	}
+
+	testCases := []TestCase{
+		{
+			// Block spanning many partitions, with blocks inside and adjacent to it
+			Input: []string{
+				"10.1.0.0/16",
+				"8.0.0.0/6",
+				"12.0.0.0/8",
+				"11.255.255.255/32",
+				"7.255.255.255/32",
+			},
+			Workers: 4,
+		},
+		{
+			// Blocks touching at partition boundaries
+			Input: []string{
+				"9.255.255.0/24",
+				"10.0.0.0/24",
+				"2001:db8:ffff:ffff::/64",
+				"2002::/64",
+				"2001:db9::/32",
+			},
+			Workers: 3,
+		},
+		{
+			Input: []string{
+				"::ffff:10.0.0.0/104",
+				"11.0.0.0/8",
+				"::ffff:0.0.0.0/80",
+				"192.0.2.0/24",
+			},
+			Workers: 2,
+		},
+		{
+			Input: []string{
+				"255.255.255.255/32",
+				"255.0.0.0/8",
+				"0.0.0.0/0",
+				"::/0",
+				"::1/128",
+			},
+			Workers: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		var nets []*net.IPNet
+		for _, cidr := range testCase.Input {
+			_, network, _ := net.ParseCIDR(cidr)
+			nets = append(nets, network)
+		}
+		expected, _ := MergeIPNets(nets)
+		output, err := mergeParallel(nets, testCase.Workers)
+		if err != nil {
+			t.Errorf("mergeParallel(%#v) failed: %s", testCase.Input, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(ipNets(expected).toCIDRs(), ipNets(output).toCIDRs()) {
+			t.Errorf("mergeParallel(%#v) expected: %#v, got: %#v", testCase.Input, ipNets(expected).toCIDRs(), ipNets(output).toCIDRs())
+		}
+	}
+
+	// Random inputs, with short prefixes to create blocks spanning partitions
+	for _, minPrefix := range []int{4, 8, 16} {
+		for _, workers := range []int{1, 2, 7, 16} {
+			nets := randomIPNets(5000, minPrefix, int64(minPrefix))
+			expected, _ := MergeIPNets(nets)
+			output, err := mergeParallel(nets, workers)
+			if err != nil {
+				t.Errorf("mergeParallel(%d, %d) failed: %s", minPrefix, workers, err.Error())
+				continue
+			}
+			if !reflect.DeepEqual(ipNets(expected).toCIDRs(), ipNets(output).toCIDRs()) {
+				t.Errorf("mergeParallel(%d, %d) differs from MergeIPNets, %d and %d networks", minPrefix, workers, len(expected), len(output))
+			}
+		}
+	}
+
+	cidrs := ipNets(randomIPNets(parallelMinNets+1, 12, 1)).toCIDRs()
+	expected, _ := MergeCIDRs(cidrs)
+	output, err := MergeCIDRsParallel(cidrs, 0)
+	if err != nil {
+		t.Errorf("MergeCIDRsParallel() failed: %s", err.Error())
+	} else if !reflect.DeepEqual(expected, output) {
+		t.Errorf("MergeCIDRsParallel() differs from MergeCIDRs, %d and %d CIDRs", len(expected), len(output))
+	}
+	if _, err := MergeCIDRsParallel(append(cidrs, "10.0.0.0/33"), 0); err == nil {
+		t.Errorf("MergeCIDRsParallel() expected error")
+	}
+}
+
+func benchmarkMerge(b *testing.B, n int, merge func([]*net.IPNet) ([]*net.IPNet, error)) {
+	nets := randomIPNets(n, 8, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := merge(nets); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMergeIPNets(b *testing.B) {
+	for _, n := range []int{1000000, 10000000} {
+		b.Run(fmt.Sprintf("%dM", n/1000000), func(b *testing.B) {
+			benchmarkMerge(b, n, MergeIPNets)
+		})
+	}
+}
+
+func BenchmarkMergeIPNetsParallel(b *testing.B) {
+	for _, n := range []int{1000000, 10000000} {
+		b.Run(fmt.Sprintf("%dM", n/1000000), func(b *testing.B) {
+			benchmarkMerge(b, n, func(nets []*net.IPNet) ([]*net.IPNet, error) {
+				return MergeIPNetsParallel(nets, 0)
+			})
+		})
+	}
+}
@@ -64,6 +64,16 @@ func TestMergeCIDRs(t *testing.T) {
 			},
 			Error: false,
 		},
+		{
+			Input: []string{
+				"255.0.0.0/8",
+				"255.255.0.0/16",
+			},
+			Output: []string{
+				"255.0.0.0/8",
+			},
+			Error: false,
+		},
 		{
 			Input: []string{
 				"192.0.129.0/24",
@@ -365,6 +375,19 @@ func TestMergeCIDRs(t *testing.T) {
 			},
 			Error: false,
 		},
+		// IPv4-mapped IPv6 networks are IPv4 networks from prefix length 96
+		{
+			Input: []string{
+				"::ffff:10.0.0.0/104",
+				"11.0.0.0/8",
+				"::ffff:0.0.0.0/90",
+			},
+			Output: []string{
+				"10.0.0.0/7",
+				"::ffc0:0:0/90",
+			},
+			Error: false,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -0,0 +1,432 @@
+// Reading of MaxMind DB (MMDB) files, as specified in
+// https://maxmind.github.io/MaxMind-DB/.
+
+package cidrman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+)
+
+// MMDB data section types.
+const (
+	mmdbExtended = 0
+	mmdbPointer  = 1
+	mmdbString   = 2
+	mmdbDouble   = 3
+	mmdbBytes    = 4
+	mmdbUint16   = 5
+	mmdbUint32   = 6
+	mmdbMap      = 7
+	mmdbInt32    = 8
+	mmdbUint64   = 9
+	mmdbUint128  = 10
+	mmdbArray    = 11
+	mmdbBool     = 14
+	mmdbFloat    = 15
+)
+
+// mmdbMetadataMarker starts the metadata section at the end of an MMDB file.
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// mmdbDataSeparatorSize is the size of the zero bytes between the search tree and the data section.
+const mmdbDataSeparatorSize = 16
+
+// mmdbMaxDepth limits the nesting of maps and arrays, and the number of chained pointers.
+const mmdbMaxDepth = 64
+
+// MMDBReader reads networks and records from a MaxMind DB file.
+type MMDBReader struct {
+	// Metadata is the decoded metadata map of the file.
+	Metadata map[string]interface{}
+
+	tree       []byte
+	data       []byte
+	nodeCount  uint32
+	recordSize uint
+	ipVersion  int
+	ipv4Start  uint32
+	ipv4Depth  int
+}
+
+// mmdbDecoder decodes values from an MMDB data section.
+type mmdbDecoder struct {
+	buf []byte
+}
+
+// decodeCtrl decodes the control byte(s) at offset and returns the type, size and offset of the payload.
+func (d *mmdbDecoder) decodeCtrl(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errors.New("Invalid MMDB data: offset out of range")
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ := int(ctrl >> 5)
+	if typ == mmdbPointer {
+		return typ, uint(ctrl), offset, nil
+	}
+	if typ == mmdbExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("Invalid MMDB data: truncated extended type")
+		}
+		typ = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("Invalid MMDB data: truncated size")
+		}
+		extra := uint(0)
+		for _, b := range d.buf[offset : offset+n] {
+			extra = extra<<8 | uint(b)
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	return typ, size, offset, nil
+}
+
+// decode decodes the value at offset and returns it with the offset after the value.
+func (d *mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errors.New("Invalid MMDB data: nested too deep")
+	}
+	typ, size, offset, err := d.decodeCtrl(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == mmdbPointer {
+		n := ((size >> 3) & 0x3) + 1
+		if offset+n > uint(len(d.buf)) {
+			return nil, 0, errors.New("Invalid MMDB data: truncated pointer")
+		}
+		ptr := uint(0)
+		if n < 4 {
+			ptr = size & 0x7
+		}
+		for _, b := range d.buf[offset : offset+n] {
+			ptr = ptr<<8 | uint(b)
+		}
+		switch n {
+		case 2:
+			ptr += 2048
+		case 3:
+			ptr += 526336
+		}
+		value, _, err := d.decode(ptr, depth+1)
+		return value, offset + n, err
+	}
+
+	switch typ {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("Invalid MMDB data: map key is not a string")
+			}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+		}
+		return m, offset, nil
+	case mmdbArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value interface{}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case mmdbBool:
+		if size > 1 {
+			return nil, 0, errors.New("Invalid MMDB data: boolean size")
+		}
+		return size == 1, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errors.New("Invalid MMDB data: truncated value")
+	}
+	payload := d.buf[offset : offset+size]
+	offset += size
+	switch typ {
+	case mmdbString:
+		return string(payload), offset, nil
+	case mmdbBytes:
+		return append([]byte(nil), payload...), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("Invalid MMDB data: double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("Invalid MMDB data: float size")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(payload)), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		if size > 8 {
+			return nil, 0, errors.New("Invalid MMDB data: integer size")
+		}
+		value := uint64(0)
+		for _, b := range payload {
+			value = value<<8 | uint64(b)
+		}
+		return value, offset, nil
+	case mmdbInt32:
+		if size > 4 {
+			return nil, 0, errors.New("Invalid MMDB data: integer size")
+		}
+		value := uint32(0)
+		for _, b := range payload {
+			value = value<<8 | uint32(b)
+		}
+		return int(int32(value)), offset, nil
+	case mmdbUint128:
+		if size > 16 {
+			return nil, 0, errors.New("Invalid MMDB data: integer size")
+		}
+		return big.NewInt(0).SetBytes(payload), offset, nil
+	}
+
+	return nil, 0, fmt.Errorf("Invalid MMDB data: unknown type %d", typ)
+}
+
+// metadataUint returns an unsigned integer from the metadata map.
+func metadataUint(metadata map[string]interface{}, key string) (uint64, error) {
+	value, ok := metadata[key].(uint64)
+	if !ok {
+		return 0, fmt.Errorf("Invalid MMDB metadata: missing %s", key)
+	}
+	return value, nil
+}
+
+// NewMMDBReader returns a reader for the MaxMind DB file contents in data.
+func NewMMDBReader(data []byte) (*MMDBReader, error) {
+	start := bytes.LastIndex(data, mmdbMetadataMarker)
+	if start < 0 {
+		return nil, errors.New("Invalid MMDB file: metadata not found")
+	}
+	d := mmdbDecoder{buf: data[start+len(mmdbMetadataMarker):]}
+	value, _, err := d.decode(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("Invalid MMDB metadata: not a map")
+	}
+
+	r := &MMDBReader{Metadata: metadata}
+	nodeCount, err := metadataUint(metadata, "node_count")
+	if err != nil {
+		return nil, err
+	}
+	recordSize, err := metadataUint(metadata, "record_size")
+	if err != nil {
+		return nil, err
+	}
+	ipVersion, err := metadataUint(metadata, "ip_version")
+	if err != nil {
+		return nil, err
+	}
+	if recordSize != 24 && recordSize != 28 && recordSize != 32 {
+		return nil, fmt.Errorf("Unsupported MMDB record size: %d", recordSize)
+	}
+	if ipVersion != 4 && ipVersion != 6 {
+		return nil, fmt.Errorf("Unsupported MMDB IP version: %d", ipVersion)
+	}
+	if nodeCount > math.MaxUint32 {
+		return nil, fmt.Errorf("Invalid MMDB node count: %d", nodeCount)
+	}
+	r.nodeCount = uint32(nodeCount)
+	r.recordSize = uint(recordSize)
+	r.ipVersion = int(ipVersion)
+
+	treeSize := nodeCount * recordSize / 4
+	if treeSize+mmdbDataSeparatorSize > uint64(start) {
+		return nil, errors.New("Invalid MMDB file: search tree out of range")
+	}
+	r.tree = data[:treeSize]
+	r.data = data[treeSize+mmdbDataSeparatorSize : start]
+
+	// IPv4 addresses are stored at ::/96 in IPv6 trees
+	r.ipv4Start = 0
+	if r.ipVersion == 6 {
+		for r.ipv4Depth < 96 && r.ipv4Start < r.nodeCount {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+			r.ipv4Depth++
+		}
+	}
+
+	return r, nil
+}
+
+// record returns the left (0) or right (1) record of a node.
+func (r *MMDBReader) record(node uint32, bit uint) uint32 {
+	switch r.recordSize {
+	case 24:
+		b := r.tree[node*6+uint32(bit)*3:]
+		return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint32(b[3]&0xf0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0f)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	default:
+		return binary.BigEndian.Uint32(r.tree[node*8+uint32(bit)*4:])
+	}
+}
+
+// resolve decodes the data record a search tree record points to.
+func (r *MMDBReader) resolve(record uint32) (interface{}, error) {
+	offset := uint(record) - uint(r.nodeCount) - mmdbDataSeparatorSize
+	if record < r.nodeCount+mmdbDataSeparatorSize || offset >= uint(len(r.data)) {
+		return nil, fmt.Errorf("Invalid MMDB search tree record: %d", record)
+	}
+	d := mmdbDecoder{buf: r.data}
+	value, _, err := d.decode(offset, 0)
+	return value, err
+}
+
+// Lookup returns the data record for an IP address, or nil if the address is not in the file.
+func (r *MMDBReader) Lookup(ip net.IP) (interface{}, error) {
+	node := uint32(0)
+	bits := ip.To16()
+	if ip4 := ip.To4(); ip4 != nil {
+		bits = ip4
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if r.ipVersion == 4 {
+		return nil, errors.New("IPv6 address lookup in an IPv4 only MMDB file")
+	}
+	if bits == nil {
+		return nil, fmt.Errorf("Invalid IP address: %v", ip)
+	}
+
+	for i := 0; i < 8*len(bits) && node < r.nodeCount; i++ {
+		node = r.record(node, uint(bits[i/8]>>(7-uint(i%8)))&1)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, errors.New("Invalid MMDB search tree: too deep")
+	}
+	return r.resolve(node)
+}
+
+// Networks returns the smallest possible list of IPNets with data records that match.
+// A nil match returns all networks with a data record. IPv4 networks in IPv6 files
+// are returned as IPv4 networks.
+// Example:
+//     swedishNets, err := reader.Networks(MMDBFieldEquals("SE", "country", "iso_code"))
+func (r *MMDBReader) Networks(match func(record interface{}) bool) ([]*net.IPNet, error) {
+	type entry struct {
+		node  uint32
+		ip    net.IP
+		depth int
+	}
+
+	width := 8 * net.IPv6len
+	if r.ipVersion == 4 {
+		width = 8 * net.IPv4len
+	}
+	matches := make(map[uint32]bool)
+	var nets []*net.IPNet
+	stack := []entry{{node: 0, ip: make(net.IP, width/8), depth: 0}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if e.node > r.nodeCount {
+			ok, seen := matches[e.node]
+			if !seen {
+				record, err := r.resolve(e.node)
+				if err != nil {
+					return nil, err
+				}
+				ok = match == nil || match(record)
+				matches[e.node] = ok
+			}
+			if ok {
+				ip := e.ip
+				prefix := e.depth
+				if r.ipVersion == 6 && prefix >= 96 && bytes.Equal(ip[:12], net.IPv6zero[:12]) {
+					// IPv4 network in ::/96
+					ip = ip[12:]
+					prefix -= 96
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(prefix, 8*len(ip))})
+			}
+			continue
+		}
+		if e.node == r.nodeCount {
+			continue
+		}
+		if e.depth >= width {
+			return nil, errors.New("Invalid MMDB search tree: too deep")
+		}
+		// Skip the aliases of the IPv4 subtree, like ::ffff:0:0/96 and 2002::/16
+		if r.ipVersion == 6 && r.ipv4Start < r.nodeCount && e.node == r.ipv4Start &&
+			(e.depth != r.ipv4Depth || !bytes.Equal(e.ip[:12], net.IPv6zero[:12])) {
+			continue
+		}
+
+		// Push right before left, to visit the networks in order
+		for bit := 1; bit >= 0; bit-- {
+			ip := make(net.IP, len(e.ip))
+			copy(ip, e.ip)
+			if bit == 1 {
+				ip[e.depth/8] |= 0x80 >> uint(e.depth%8)
+			}
+			stack = append(stack, entry{node: r.record(e.node, uint(bit)), ip: ip, depth: e.depth + 1})
+		}
+	}
+
+	return MergeIPNets(nets)
+}
+
+// MMDBFieldEquals returns a Networks match function for records where the field
+// at the path of map keys is equal to value. Unsigned integers in records are uint64.
+func MMDBFieldEquals(value interface{}, path ...string) func(record interface{}) bool {
+	return func(record interface{}) bool {
+		for _, key := range path {
+			m, ok := record.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if record, ok = m[key]; !ok {
+				return false
+			}
+		}
+		return reflect.DeepEqual(record, value)
+	}
+}
@@ -0,0 +1,282 @@
+// go test -v -run="TestMMDB"
+
+package cidrman
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMMDB(t *testing.T) {
+	type Entry struct {
+		CIDRs  []string
+		Record interface{}
+	}
+	type Lookup struct {
+		IP     string
+		Record interface{}
+	}
+	type TestCase struct {
+		Entries   []Entry
+		IPVersion uint64
+		Match     func(record interface{}) bool
+		Output    []string
+		Lookups   []Lookup
+	}
+
+	se := map[string]interface{}{"country": map[string]interface{}{"iso_code": "SE"}}
+	no := map[string]interface{}{"country": map[string]interface{}{"iso_code": "NO"}}
+
+	testCases := []TestCase{
+		{
+			Entries:   nil,
+			IPVersion: 4,
+			Match:     nil,
+			Output:    nil,
+			Lookups:   []Lookup{{"192.0.2.1", nil}},
+		},
+		{
+			Entries: []Entry{
+				{[]string{"192.0.2.0/24", "198.51.100.0/23"}, se},
+				{[]string{"203.0.113.0/24"}, no},
+			},
+			IPVersion: 4,
+			Match:     MMDBFieldEquals("SE", "country", "iso_code"),
+			Output: []string{
+				"192.0.2.0/24",
+				"198.51.100.0/23",
+			},
+			Lookups: []Lookup{
+				{"192.0.2.1", se},
+				{"198.51.101.255", se},
+				{"203.0.113.7", no},
+				{"10.0.0.1", nil},
+			},
+		},
+		{
+			// Later networks replace the parts of earlier networks they overlap
+			Entries: []Entry{
+				{[]string{"10.0.0.0/8"}, se},
+				{[]string{"10.1.0.0/16"}, no},
+			},
+			IPVersion: 4,
+			Match:     MMDBFieldEquals("SE", "country", "iso_code"),
+			Output: []string{
+				"10.0.0.0/16",
+				"10.2.0.0/15",
+				"10.4.0.0/14",
+				"10.8.0.0/13",
+				"10.16.0.0/12",
+				"10.32.0.0/11",
+				"10.64.0.0/10",
+				"10.128.0.0/9",
+			},
+			Lookups: []Lookup{
+				{"10.0.0.1", se},
+				{"10.1.0.1", no},
+			},
+		},
+		{
+			Entries: []Entry{
+				{[]string{"0.0.0.0/0"}, "everything"},
+			},
+			IPVersion: 4,
+			Match:     nil,
+			Output: []string{
+				"0.0.0.0/0",
+			},
+			Lookups: []Lookup{
+				{"255.255.255.255", "everything"},
+			},
+		},
+		// Mixed IPv4 and IPv6 tests
+		{
+			Entries: []Entry{
+				{[]string{"192.0.2.0/24", "2001:db8::/32"}, se},
+				{[]string{"2001:db8:1::/48"}, no},
+			},
+			IPVersion: 6,
+			Match:     MMDBFieldEquals("SE", "country", "iso_code"),
+			Output: []string{
+				"192.0.2.0/24",
+				"2001:db8::/48",
+				"2001:db8:2::/47",
+				"2001:db8:4::/46",
+				"2001:db8:8::/45",
+				"2001:db8:10::/44",
+				"2001:db8:20::/43",
+				"2001:db8:40::/42",
+				"2001:db8:80::/41",
+				"2001:db8:100::/40",
+				"2001:db8:200::/39",
+				"2001:db8:400::/38",
+				"2001:db8:800::/37",
+				"2001:db8:1000::/36",
+				"2001:db8:2000::/35",
+				"2001:db8:4000::/34",
+				"2001:db8:8000::/33",
+			},
+			Lookups: []Lookup{
+				{"192.0.2.1", se},
+				{"2001:db8:1::1", no},
+				{"2001:db8:ffff::1", se},
+				{"2001:db9::1", nil},
+			},
+		},
+		{
+			Entries: []Entry{
+				{[]string{"2001:db8::/32"}, map[string]interface{}{
+					"array":   []interface{}{"a", uint64(1), true},
+					"bytes":   []byte{1, 2, 3},
+					"double":  1.5,
+					"float":   float32(2.5),
+					"int32":   -7,
+					"long":    "a string longer than twenty-nine bytes, with an extended size",
+					"uint16":  uint16(80),
+					"uint32":  uint32(65536),
+					"uint64":  uint64(1) << 40,
+					"uint128": big.NewInt(0).Lsh(big.NewInt(1), 100),
+				}},
+			},
+			IPVersion: 6,
+			Match:     MMDBFieldEquals(uint64(80), "uint16"),
+			Output: []string{
+				"2001:db8::/32",
+			},
+			Lookups: []Lookup{
+				{"2001:db8::1", map[string]interface{}{
+					"array":   []interface{}{"a", uint64(1), true},
+					"bytes":   []byte{1, 2, 3},
+					"double":  1.5,
+					"float":   float32(2.5),
+					"int32":   -7,
+					"long":    "a string longer than twenty-nine bytes, with an extended size",
+					"uint16":  uint64(80),
+					"uint32":  uint64(65536),
+					"uint64":  uint64(1) << 40,
+					"uint128": big.NewInt(0).Lsh(big.NewInt(1), 100),
+				}},
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		w := NewMMDBWriter("Test")
+		w.BuildEpoch = time.Unix(1, 0)
+		for _, entry := range testCase.Entries {
+			var nets []*net.IPNet
+			for _, cidr := range entry.CIDRs {
+				_, network, _ := net.ParseCIDR(cidr)
+				nets = append(nets, network)
+			}
+			if err := w.InsertIPNets(nets, entry.Record); err != nil {
+				t.Errorf("InsertIPNets(%d, %#v) failed: %s", i, entry.CIDRs, err.Error())
+			}
+		}
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Errorf("WriteTo(%d) failed: %s", i, err.Error())
+			continue
+		}
+
+		r, err := NewMMDBReader(buf.Bytes())
+		if err != nil {
+			t.Errorf("NewMMDBReader(%d) failed: %s", i, err.Error())
+			continue
+		}
+		if r.Metadata["ip_version"] != testCase.IPVersion || r.Metadata["database_type"] != "Test" || r.Metadata["build_epoch"] != uint64(1) {
+			t.Errorf("NewMMDBReader(%d) unexpected metadata: %#v", i, r.Metadata)
+		}
+
+		nets, err := r.Networks(testCase.Match)
+		if err != nil {
+			t.Errorf("Networks(%d) failed: %s", i, err.Error())
+			continue
+		}
+		if output := ipNets(nets).toCIDRs(); !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("Networks(%d) expected: %#v, got: %#v", i, testCase.Output, output)
+		}
+		for _, lookup := range testCase.Lookups {
+			record, err := r.Lookup(net.ParseIP(lookup.IP))
+			if err != nil {
+				t.Errorf("Lookup(%d, %s) failed: %s", i, lookup.IP, err.Error())
+				continue
+			}
+			if !reflect.DeepEqual(lookup.Record, record) {
+				t.Errorf("Lookup(%d, %s) expected: %#v, got: %#v", i, lookup.IP, lookup.Record, record)
+			}
+		}
+	}
+}
+
+func TestMMDBDecode(t *testing.T) {
+	type TestCase struct {
+		Input  []byte
+		Offset uint
+		Output interface{}
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			// Map with a pointer to an earlier string as value
+			Input:  []byte{0x41, 'a', 0xe1, 0x41, 'k', 0x20, 0x00},
+			Offset: 2,
+			Output: map[string]interface{}{"k": "a"},
+		},
+		{
+			// Two byte pointer out of range
+			Input:  []byte{0x28, 0x00, 0x00},
+			Offset: 0,
+			Output: nil,
+			Error:  true,
+		},
+		{
+			// Extended type 14 (boolean) true
+			Input:  []byte{0x01, 0x07},
+			Output: true,
+		},
+		{
+			// Extended type 8 (int32) -1
+			Input:  []byte{0x04, 0x01, 0xff, 0xff, 0xff, 0xff},
+			Output: -1,
+		},
+		{
+			// Pointer loop
+			Input: []byte{0x20, 0x00},
+			Error: true,
+		},
+		{
+			// Truncated string
+			Input: []byte{0x45, 'a'},
+			Error: true,
+		},
+		{
+			// Unknown extended type
+			Input: []byte{0x00, 0x06},
+			Error: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		d := mmdbDecoder{buf: testCase.Input}
+		output, _, err := d.decode(testCase.Offset, 0)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("decode(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("decode(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("decode(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
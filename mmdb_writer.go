@@ -0,0 +1,356 @@
+// Writing of MaxMind DB (MMDB) files, as specified in
+// https://maxmind.github.io/MaxMind-DB/.
+
+package cidrman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"sort"
+	"time"
+)
+
+// mmdbNode is a node of the search tree under construction. A node is either a leaf,
+// with or without a data record, or has two children.
+type mmdbNode struct {
+	children [2]*mmdbNode
+	record   interface{}
+	number   uint32
+}
+
+// MMDBWriter builds a MaxMind DB file from networks annotated with data records.
+// Example:
+//     w := NewMMDBWriter("Netnod-Country")
+//     err := w.InsertIPNets(swedishNets, map[string]interface{}{"country": map[string]interface{}{"iso_code": "SE"}})
+//     _, err = w.WriteTo(file)
+type MMDBWriter struct {
+	// DatabaseType is the database_type metadata field.
+	DatabaseType string
+	// Description is the description metadata field, by language.
+	Description map[string]string
+	// Languages is the languages metadata field.
+	Languages []string
+	// BuildEpoch is the build_epoch metadata field, the zero value uses the current time.
+	BuildEpoch time.Time
+
+	root *mmdbNode
+	ipv6 bool
+}
+
+// NewMMDBWriter returns a new writer for an MMDB file of the database type.
+func NewMMDBWriter(databaseType string) *MMDBWriter {
+	return &MMDBWriter{
+		DatabaseType: databaseType,
+		root:         &mmdbNode{},
+	}
+}
+
+// Insert adds a network with its data record. Records are maps, arrays, strings, bools, []byte,
+// float32, float64, int32, int, uint16, uint32, uint64, uint and *big.Int (uint128) values.
+// A network inserted later replaces the parts of earlier networks it overlaps.
+// IPv4 networks are stored at ::/96 when the file also holds IPv6 networks.
+func (w *MMDBWriter) Insert(n *net.IPNet, record interface{}) error {
+	if record == nil {
+		return fmt.Errorf("Missing data record for %v", n)
+	}
+	if _, err := encodeMMDB(nil, record); err != nil {
+		return err
+	}
+
+	ip := n.IP.To16()
+	if len(n.Mask) == net.IPv4len {
+		ip = n.IP.To4()
+	} else {
+		w.ipv6 = true
+	}
+	if ip == nil {
+		return fmt.Errorf("Invalid IP address: %v", n.IP)
+	}
+	prefix, bits := n.Mask.Size()
+	if bits != 8*len(ip) {
+		return fmt.Errorf("Invalid mask for %v", n)
+	}
+
+	// IPv4 networks are kept at ::/96, they are moved to the root when the file is IPv4 only
+	ip16 := make(net.IP, net.IPv6len)
+	copy(ip16[net.IPv6len-len(ip):], ip)
+	prefix += 8 * (net.IPv6len - len(ip))
+
+	node := w.root
+	for i := 0; i < prefix; i++ {
+		if node.children[0] == nil {
+			// Split a leaf, both halves keep the record of the leaf
+			node.children[0] = &mmdbNode{record: node.record}
+			node.children[1] = &mmdbNode{record: node.record}
+			node.record = nil
+		}
+		node = node.children[ip16[i/8]>>(7-uint(i%8))&1]
+	}
+	node.children = [2]*mmdbNode{}
+	node.record = record
+	return nil
+}
+
+// InsertIPNets adds a list of networks, typically from MergeIPNets or RemoveIPNets, with the same data record.
+func (w *MMDBWriter) InsertIPNets(nets []*net.IPNet, record interface{}) error {
+	for _, n := range nets {
+		if err := w.Insert(n, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMMDBCtrl appends the control byte(s) for a type and payload size.
+func encodeMMDBCtrl(buf []byte, typ int, size int) []byte {
+	var ctrl []byte
+	switch {
+	case size < 29:
+		ctrl = []byte{byte(size)}
+	case size < 285:
+		ctrl = []byte{29, byte(size - 29)}
+	case size < 65821:
+		ctrl = []byte{30, byte((size - 285) >> 8), byte(size - 285)}
+	default:
+		s := size - 65821
+		ctrl = []byte{31, byte(s >> 16), byte(s >> 8), byte(s)}
+	}
+	if typ > 7 {
+		buf = append(buf, ctrl[0], byte(typ-7))
+	} else {
+		buf = append(buf, byte(typ<<5)|ctrl[0])
+	}
+	return append(buf, ctrl[1:]...)
+}
+
+// encodeMMDBUint appends an unsigned integer with leading zero bytes removed.
+func encodeMMDBUint(buf []byte, typ int, value uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], value)
+	i := 0
+	for i < len(b) && b[i] == 0 {
+		i++
+	}
+	buf = encodeMMDBCtrl(buf, typ, len(b)-i)
+	return append(buf, b[i:]...)
+}
+
+// encodeMMDB appends the MMDB data section encoding of a value.
+func encodeMMDB(buf []byte, value interface{}) ([]byte, error) {
+	var err error
+	switch v := value.(type) {
+	case string:
+		buf = encodeMMDBCtrl(buf, mmdbString, len(v))
+		buf = append(buf, v...)
+	case []byte:
+		buf = encodeMMDBCtrl(buf, mmdbBytes, len(v))
+		buf = append(buf, v...)
+	case bool:
+		if v {
+			buf = encodeMMDBCtrl(buf, mmdbBool, 1)
+		} else {
+			buf = encodeMMDBCtrl(buf, mmdbBool, 0)
+		}
+	case float64:
+		buf = encodeMMDBCtrl(buf, mmdbDouble, 8)
+		buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], math.Float64bits(v))
+	case float32:
+		buf = encodeMMDBCtrl(buf, mmdbFloat, 4)
+		buf = append(buf, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], math.Float32bits(v))
+	case uint16:
+		buf = encodeMMDBUint(buf, mmdbUint16, uint64(v))
+	case uint32:
+		buf = encodeMMDBUint(buf, mmdbUint32, uint64(v))
+	case uint64:
+		buf = encodeMMDBUint(buf, mmdbUint64, v)
+	case uint:
+		buf = encodeMMDBUint(buf, mmdbUint64, uint64(v))
+	case int32:
+		buf = encodeMMDBCtrl(buf, mmdbInt32, 4)
+		buf = append(buf, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(v))
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return nil, fmt.Errorf("MMDB int32 out of range: %d", v)
+		}
+		return encodeMMDB(buf, int32(v))
+	case *big.Int:
+		if v.Sign() < 0 || v.BitLen() > widthUInt128 {
+			return nil, fmt.Errorf("MMDB uint128 out of range: %v", v)
+		}
+		b := v.Bytes()
+		buf = encodeMMDBCtrl(buf, mmdbUint128, len(b))
+		buf = append(buf, b...)
+	case []interface{}:
+		buf = encodeMMDBCtrl(buf, mmdbArray, len(v))
+		for _, e := range v {
+			if buf, err = encodeMMDB(buf, e); err != nil {
+				return nil, err
+			}
+		}
+	case []string:
+		buf = encodeMMDBCtrl(buf, mmdbArray, len(v))
+		for _, e := range v {
+			buf, _ = encodeMMDB(buf, e)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = encodeMMDBCtrl(buf, mmdbMap, len(v))
+		for _, key := range keys {
+			buf, _ = encodeMMDB(buf, key)
+			if buf, err = encodeMMDB(buf, v[key]); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for key, e := range v {
+			m[key] = e
+		}
+		return encodeMMDB(buf, m)
+	default:
+		return nil, fmt.Errorf("Unsupported MMDB data type: %T", value)
+	}
+	return buf, nil
+}
+
+// WriteTo writes the MMDB file, implementing io.WriterTo.
+func (w *MMDBWriter) WriteTo(out io.Writer) (int64, error) {
+	root := w.root
+	ipVersion := 6
+	if !w.ipv6 {
+		// IPv4 only file, the tree starts at ::/96
+		ipVersion = 4
+		depth := 0
+		for depth < 96 && root.children[0] != nil {
+			root = root.children[0]
+			depth++
+		}
+		if depth < 96 {
+			// Nothing was inserted
+			root = &mmdbNode{}
+		}
+	}
+
+	// Number the nodes and encode the data records, identical records are stored once
+	var nodes []*mmdbNode
+	var data []byte
+	offsets := make(map[string]uint32)
+	records := make(map[*mmdbNode]uint32)
+	queue := []*mmdbNode{root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.children[0] == nil {
+			if node.record == nil {
+				continue
+			}
+			encoded, err := encodeMMDB(nil, node.record)
+			if err != nil {
+				return 0, err
+			}
+			offset, ok := offsets[string(encoded)]
+			if !ok {
+				offset = uint32(len(data))
+				offsets[string(encoded)] = offset
+				data = append(data, encoded...)
+			}
+			records[node] = offset
+			continue
+		}
+		node.number = uint32(len(nodes))
+		nodes = append(nodes, node)
+		queue = append(queue, node.children[0], node.children[1])
+	}
+	// An empty tree still needs a root node
+	if len(nodes) == 0 {
+		if root.record == nil {
+			root = &mmdbNode{children: [2]*mmdbNode{{}, {}}}
+		} else {
+			root = &mmdbNode{children: [2]*mmdbNode{root, root}}
+		}
+		nodes = append(nodes, root)
+	}
+
+	nodeCount := uint32(len(nodes))
+	value := func(node *mmdbNode) uint64 {
+		if node.children[0] != nil {
+			return uint64(node.number)
+		}
+		if node.record == nil {
+			return uint64(nodeCount)
+		}
+		return uint64(nodeCount) + mmdbDataSeparatorSize + uint64(records[node])
+	}
+	maxValue := uint64(nodeCount) + mmdbDataSeparatorSize + uint64(len(data))
+	recordSize := 24
+	if maxValue >= 1<<28 {
+		recordSize = 32
+	} else if maxValue >= 1<<24 {
+		recordSize = 28
+	}
+	if maxValue >= 1<<32 {
+		return 0, fmt.Errorf("MMDB file too large: %d nodes, %d bytes of data", nodeCount, len(data))
+	}
+
+	var buf bytes.Buffer
+	record := make([]byte, 8)
+	for _, node := range nodes {
+		left, right := value(node.children[0]), value(node.children[1])
+		switch recordSize {
+		case 24:
+			record = append(record[:0], byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			record = append(record[:0], byte(left>>16), byte(left>>8), byte(left), byte(left>>20)&0xf0|byte(right>>24)&0x0f, byte(right>>16), byte(right>>8), byte(right))
+		default:
+			record = record[:8]
+			binary.BigEndian.PutUint32(record, uint32(left))
+			binary.BigEndian.PutUint32(record[4:], uint32(right))
+		}
+		buf.Write(record)
+	}
+	buf.Write(make([]byte, mmdbDataSeparatorSize))
+	buf.Write(data)
+
+	buildEpoch := w.BuildEpoch
+	if buildEpoch.IsZero() {
+		buildEpoch = time.Now()
+	}
+	languages := w.Languages
+	if languages == nil {
+		languages = []string{}
+	}
+	description := w.Description
+	if description == nil {
+		description = map[string]string{}
+	}
+	metadata, err := encodeMMDB(nil, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(buildEpoch.Unix()),
+		"database_type":               w.DatabaseType,
+		"description":                 description,
+		"ip_version":                  uint16(ipVersion),
+		"languages":                   languages,
+		"node_count":                  nodeCount,
+		"record_size":                 uint16(recordSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+	buf.Write(mmdbMetadataMarker)
+	buf.Write(metadata)
+
+	return buf.WriteTo(out)
+}
@@ -0,0 +1,156 @@
+// Reference-counted sets of IP networks, for overlapping announcements from several sources.
+
+package cidrman
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// covered returns the coalesced intervals covered by at least k references.
+func covered(refs map[cidrBlockValue]int, k int) cidrBlockValues {
+	edges := make(blockEdges, 0, 2*len(refs))
+	for block, count := range refs {
+		edges = edges.appendBlock(block, count)
+	}
+	sort.Sort(edges)
+
+	// Sweep the edges, keeping the number of references at the current address
+	var blocks cidrBlockValues
+	var first uint128
+	count := 0
+	for _, edge := range edges {
+		if edge.start {
+			count += edge.value
+			if count >= k && count-edge.value < k {
+				first = edge.addr
+			}
+			continue
+		}
+		count -= edge.value
+		if count < k && count+edge.value >= k {
+			n := len(blocks)
+			if n > 0 && blocks[n-1].last.addOne() == first {
+				// Adjacent to the previous interval, when an interval starts right after another ends
+				blocks[n-1].last = edge.addr
+			} else {
+				blocks = append(blocks, cidrBlockValue{first, edge.addr})
+			}
+		}
+	}
+	return blocks
+}
+
+// MultiSet is a reference-counted set of mixed IP networks. Every Add of a network must be
+// matched by a Remove of the same network before its addresses leave the set, so that withdrawing
+// a network announced by one source keeps the addresses still announced by other sources.
+// Example:
+//     s := NewMultiSet()
+//     s.AddCIDR("10.0.0.0/8")     // Source A
+//     s.AddCIDR("10.1.0.0/16")    // Source B
+//     s.RemoveCIDR("10.0.0.0/8")  // Source A withdraws
+//     s.CIDRs(1)                  // [10.1.0.0/16]
+type MultiSet struct {
+	refs4 map[cidrBlockValue]int
+	refs6 map[cidrBlockValue]int
+}
+
+// NewMultiSet returns a new, empty multiset.
+func NewMultiSet() *MultiSet {
+	return &MultiSet{
+		refs4: make(map[cidrBlockValue]int),
+		refs6: make(map[cidrBlockValue]int),
+	}
+}
+
+// refs returns the block of a network and the reference counts of its address family.
+func (s *MultiSet) refs(n *net.IPNet) (cidrBlockValue, map[cidrBlockValue]int, error) {
+	block4s, block6s, err := loadBlockValues([]*net.IPNet{n}, nil, nil)
+	if err != nil {
+		return cidrBlockValue{}, nil, err
+	}
+	if len(block4s) > 0 {
+		return block4s[0], s.refs4, nil
+	}
+	return block6s[0], s.refs6, nil
+}
+
+// Add adds a reference to a network.
+func (s *MultiSet) Add(n *net.IPNet) error {
+	block, refs, err := s.refs(n)
+	if err != nil {
+		return err
+	}
+	refs[block]++
+	return nil
+}
+
+// Remove removes a reference to a network, added earlier with Add.
+func (s *MultiSet) Remove(n *net.IPNet) error {
+	block, refs, err := s.refs(n)
+	if err != nil {
+		return err
+	}
+	if refs[block] == 0 {
+		return fmt.Errorf("Network not in multiset: %v", n)
+	}
+	refs[block]--
+	if refs[block] == 0 {
+		delete(refs, block)
+	}
+	return nil
+}
+
+// AddCIDR adds a reference to a CIDR block.
+func (s *MultiSet) AddCIDR(cidr string) error {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	return s.Add(network)
+}
+
+// RemoveCIDR removes a reference to a CIDR block, added earlier with AddCIDR.
+func (s *MultiSet) RemoveCIDR(cidr string) error {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	return s.Remove(network)
+}
+
+// Count returns the number of references covering the IP address.
+func (s *MultiSet) Count(ip net.IP) int {
+	addr, width := ipToUInt128(ip)
+	refs := s.refs4
+	switch width {
+	case widthUInt128:
+		refs = s.refs6
+	case 0:
+		return 0
+	}
+
+	count := 0
+	for block, n := range refs {
+		if !addr.less(block.first) && !block.last.less(addr) {
+			count += n
+		}
+	}
+	return count
+}
+
+// IPNets returns the smallest possible list of IPNets covered by at least k references, IPv4 before IPv6.
+// A k of 1 or less returns all networks of the multiset.
+func (s *MultiSet) IPNets(k int) []*net.IPNet {
+	if k < 1 {
+		k = 1
+	}
+	nets := appendIntervalIPNets(nil, covered(s.refs4, k), widthUInt32)
+	return appendIntervalIPNets(nets, covered(s.refs6, k), widthUInt128)
+}
+
+// CIDRs returns the smallest possible list of CIDRs covered by at least k references, IPv4 before IPv6.
+func (s *MultiSet) CIDRs(k int) []string {
+	return ipNets(s.IPNets(k)).toCIDRs()
+}
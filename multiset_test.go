@@ -0,0 +1,149 @@
+// go test -v -run="TestMultiSet"
+
+package cidrman
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestMultiSet(t *testing.T) {
+	type TestCase struct {
+		Adds    []string
+		Removes []string
+		Outputs map[int][]string
+		Error   bool
+	}
+
+	testCases := []TestCase{
+		{
+			Adds:    nil,
+			Removes: nil,
+			Outputs: map[int][]string{1: nil},
+			Error:   false,
+		},
+		{
+			// Withdrawing one source keeps the space of the other
+			Adds:    []string{"10.0.0.0/8", "10.1.0.0/16"},
+			Removes: []string{"10.0.0.0/8"},
+			Outputs: map[int][]string{
+				0: {"10.1.0.0/16"},
+				1: {"10.1.0.0/16"},
+				2: nil,
+			},
+			Error: false,
+		},
+		{
+			Adds: []string{
+				"192.0.2.0/24",
+				"192.0.2.0/25",
+				"192.0.2.64/26",
+				"192.0.2.128/25",
+				"192.0.2.0/24",
+				"2001:db8::/32",
+				"2001:db8::/33",
+			},
+			Removes: nil,
+			Outputs: map[int][]string{
+				1: {"192.0.2.0/24", "2001:db8::/32"},
+				2: {"192.0.2.0/24", "2001:db8::/33"},
+				3: {"192.0.2.0/24"},
+				4: {"192.0.2.64/26"},
+				5: nil,
+			},
+			Error: false,
+		},
+		{
+			// Adjacent intervals at the threshold are coalesced
+			Adds:    []string{"10.0.0.0/24", "10.0.0.0/24", "10.0.1.0/24", "10.0.1.0/25", "10.0.1.0/24"},
+			Removes: []string{"10.0.1.0/24"},
+			Outputs: map[int][]string{
+				2: {"10.0.0.0/24", "10.0.1.0/25"},
+				1: {"10.0.0.0/23"},
+			},
+			Error: false,
+		},
+		{
+			Adds:    []string{"255.255.255.0/24", "0.0.0.0/0", "::/0", "ffff::/16"},
+			Removes: nil,
+			Outputs: map[int][]string{
+				2: {"255.255.255.0/24", "ffff::/16"},
+			},
+			Error: false,
+		},
+		{
+			// Removing a network that was not added
+			Adds:    []string{"10.0.0.0/8"},
+			Removes: []string{"10.0.0.0/16"},
+			Outputs: nil,
+			Error:   true,
+		},
+		{
+			Adds:    []string{"10.0.0.0/33"},
+			Removes: nil,
+			Outputs: nil,
+			Error:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		s := NewMultiSet()
+		var err error
+		for _, cidr := range testCase.Adds {
+			if err = s.AddCIDR(cidr); err != nil {
+				break
+			}
+		}
+		for _, cidr := range testCase.Removes {
+			if err != nil {
+				break
+			}
+			err = s.RemoveCIDR(cidr)
+		}
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("MultiSet(%#v, %#v) failed: %s", testCase.Adds, testCase.Removes, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("MultiSet(%#v, %#v) expected error", testCase.Adds, testCase.Removes)
+			continue
+		}
+		for k, expected := range testCase.Outputs {
+			if output := s.CIDRs(k); !reflect.DeepEqual(expected, output) {
+				t.Errorf("CIDRs(%#v, %#v, %d) expected: %#v, got: %#v", testCase.Adds, testCase.Removes, k, expected, output)
+			}
+		}
+	}
+}
+
+func TestMultiSetRandom(t *testing.T) {
+	s := NewMultiSet()
+	nets := randomIPNets(300, 4, 1)
+	for _, net := range nets {
+		s.Add(net)
+	}
+	for _, net := range nets[:100] {
+		s.Remove(net)
+	}
+
+	// An address is in the cover for k when it is covered by at least k references
+	for k := 1; k <= 4; k++ {
+		cover := s.IPNets(k)
+		for _, ipNet := range randomIPNets(500, 32, int64(k)) {
+			ip := ipNet.IP
+			contained := false
+			for _, net := range cover {
+				contained = contained || net.Contains(ip)
+			}
+			if count := s.Count(ip); (count >= k) != contained {
+				t.Errorf("IPNets(%d) contains %s: %v, with count %d", k, ip, contained, count)
+			}
+		}
+	}
+	if count := s.Count(net.IP{1, 2, 3}); count != 0 {
+		t.Errorf("Count() of invalid IP address expected: 0, got: %d", count)
+	}
+}
@@ -0,0 +1,311 @@
+// Incremental maintenance of a set of IP networks.
+// The set is kept as coalesced intervals in a treap (randomized balanced binary search tree)
+// per address family, ordered by first address, so that adding or removing a prefix takes
+// O(log n) expected time plus the number of intervals it joins or cuts.
+
+package cidrman
+
+import (
+	"net"
+)
+
+// intervalNode is a node of a treap of disjoint, non-adjacent intervals ordered by first address.
+type intervalNode struct {
+	block       cidrBlockValue
+	priority    uint64
+	left, right *intervalNode
+}
+
+// splitIntervals splits a treap into the intervals starting before key and the rest.
+func splitIntervals(t *intervalNode, key uint128) (*intervalNode, *intervalNode) {
+	if t == nil {
+		return nil, nil
+	}
+	if t.block.first.less(key) {
+		l, r := splitIntervals(t.right, key)
+		t.right = l
+		return t, r
+	}
+	l, r := splitIntervals(t.left, key)
+	t.left = r
+	return l, t
+}
+
+// splitIntervalsAfter splits a treap into the intervals starting at or before addr and the rest.
+func splitIntervalsAfter(t *intervalNode, addr uint128) (*intervalNode, *intervalNode) {
+	if addr.isMax() {
+		return t, nil
+	}
+	return splitIntervals(t, addr.addOne())
+}
+
+// joinIntervals joins two treaps where all intervals of l are before those of r.
+func joinIntervals(l, r *intervalNode) *intervalNode {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	if l.priority > r.priority {
+		l.right = joinIntervals(l.right, r)
+		return l
+	}
+	r.left = joinIntervals(l, r.left)
+	return r
+}
+
+// popLastInterval removes the last interval of a treap and returns the rest and the interval.
+func popLastInterval(t *intervalNode) (*intervalNode, *intervalNode) {
+	if t.right == nil {
+		return t.left, t
+	}
+	var last *intervalNode
+	t.right, last = popLastInterval(t.right)
+	return t, last
+}
+
+// lastInterval returns the last interval of a treap, nil for an empty treap.
+func lastInterval(t *intervalNode) *intervalNode {
+	for t != nil && t.right != nil {
+		t = t.right
+	}
+	return t
+}
+
+// appendIntervals appends the intervals of a treap in ascending order.
+func appendIntervals(blocks cidrBlockValues, t *intervalNode) cidrBlockValues {
+	if t == nil {
+		return blocks
+	}
+	blocks = appendIntervals(blocks, t.left)
+	blocks = append(blocks, t.block)
+	return appendIntervals(blocks, t.right)
+}
+
+// appendIntervalIPNets appends the CIDR blocks covering the intervals to nets.
+func appendIntervalIPNets(nets []*net.IPNet, blocks cidrBlockValues, width uint) []*net.IPNet {
+	var ws Workspace
+	var values []net.IPNet
+	for _, block := range blocks {
+		values = ws.appendRange(values, block.first, block.last, width)
+	}
+	for i := range values {
+		nets = append(nets, &values[i])
+	}
+	return nets
+}
+
+// SetDelta is a change of the minimal CIDR cover of a MutableSet,
+// the CIDR blocks that were added to and removed from the cover.
+type SetDelta struct {
+	Added   []*net.IPNet
+	Removed []*net.IPNet
+}
+
+// newSetDelta returns the change from the cover of the removed intervals to the cover of the
+// added intervals. CIDR blocks in both covers are left out.
+func newSetDelta(removed, added cidrBlockValues, width uint) SetDelta {
+	removedNets := appendIntervalIPNets(nil, removed, width)
+	addedNets := appendIntervalIPNets(nil, added, width)
+
+	kept := make(map[string]bool)
+	for _, net := range removedNets {
+		kept[net.String()] = false
+	}
+	var delta SetDelta
+	for _, net := range addedNets {
+		if _, ok := kept[net.String()]; ok {
+			kept[net.String()] = true
+		} else {
+			delta.Added = append(delta.Added, net)
+		}
+	}
+	for _, net := range removedNets {
+		if !kept[net.String()] {
+			delta.Removed = append(delta.Removed, net)
+		}
+	}
+	return delta
+}
+
+// MutableSet is a set of mixed IP networks that is updated incrementally,
+// for example from a stream of route announcements and withdrawals.
+// Subscribers are notified of the change of the minimal CIDR cover after each update.
+// A MutableSet must not be used concurrently.
+// Example:
+//     s := NewMutableSet()
+//     s.Subscribe(func(delta SetDelta) { log.Printf("+%v -%v", delta.Added, delta.Removed) })
+//     s.AddCIDR("192.0.2.0/25")
+//     s.AddCIDR("192.0.2.128/25")     // +[192.0.2.0/24] -[192.0.2.0/25]
+//     s.RemoveCIDR("192.0.2.64/26")   // +[192.0.2.0/26 192.0.2.128/25] -[192.0.2.0/24]
+type MutableSet struct {
+	root4       *intervalNode
+	root6       *intervalNode
+	seed        uint64
+	subscribers []func(SetDelta)
+}
+
+// NewMutableSet returns a new, empty set.
+func NewMutableSet() *MutableSet {
+	return &MutableSet{seed: 0x9e3779b97f4a7c15}
+}
+
+// newNode returns a new treap node with a pseudo-random priority (xorshift64).
+func (s *MutableSet) newNode(block cidrBlockValue) *intervalNode {
+	s.seed ^= s.seed << 13
+	s.seed ^= s.seed >> 7
+	s.seed ^= s.seed << 17
+	return &intervalNode{block: block, priority: s.seed}
+}
+
+// Subscribe registers a function that is called with the delta of every update that changes the cover.
+func (s *MutableSet) Subscribe(fn func(SetDelta)) {
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// notify calls the subscribers with a non-empty delta.
+func (s *MutableSet) notify(delta SetDelta) {
+	if len(delta.Added) == 0 && len(delta.Removed) == 0 {
+		return
+	}
+	for _, fn := range s.subscribers {
+		fn(delta)
+	}
+}
+
+// root returns the treap of the address family of the network and its width.
+func (s *MutableSet) root(n *net.IPNet) (cidrBlockValue, **intervalNode, uint, error) {
+	block4s, block6s, err := loadBlockValues([]*net.IPNet{n}, nil, nil)
+	if err != nil {
+		return cidrBlockValue{}, nil, 0, err
+	}
+	if len(block4s) > 0 {
+		return block4s[0], &s.root4, widthUInt32, nil
+	}
+	return block6s[0], &s.root6, widthUInt128, nil
+}
+
+// Add adds a network to the set and returns the change of the minimal CIDR cover.
+func (s *MutableSet) Add(n *net.IPNet) (SetDelta, error) {
+	block, root, width, err := s.root(n)
+	if err != nil {
+		return SetDelta{}, err
+	}
+
+	var removed cidrBlockValues
+	l, r := splitIntervals(*root, block.first)
+	// The interval before the network when it overlaps or touches the network
+	if last := lastInterval(l); last != nil && (last.block.last.isMax() || !last.block.last.addOne().less(block.first)) {
+		if !last.block.last.less(block.last) {
+			// Already in the set
+			*root = joinIntervals(l, r)
+			return SetDelta{}, nil
+		}
+		l, last = popLastInterval(l)
+		removed = append(removed, last.block)
+		block.first = last.block.first
+	}
+	// The intervals starting inside or right after the network
+	m := r
+	if !block.last.isMax() {
+		m, r = splitIntervalsAfter(r, block.last.addOne())
+	} else {
+		r = nil
+	}
+	removed = appendIntervals(removed, m)
+	if last := lastInterval(m); last != nil && block.last.less(last.block.last) {
+		block.last = last.block.last
+	}
+	*root = joinIntervals(joinIntervals(l, s.newNode(block)), r)
+
+	delta := newSetDelta(removed, cidrBlockValues{block}, width)
+	s.notify(delta)
+	return delta, nil
+}
+
+// Remove removes a network from the set and returns the change of the minimal CIDR cover.
+// The addresses of the network are removed even when they were added as part of other networks.
+func (s *MutableSet) Remove(n *net.IPNet) (SetDelta, error) {
+	block, root, width, err := s.root(n)
+	if err != nil {
+		return SetDelta{}, err
+	}
+
+	var removed, added cidrBlockValues
+	l, r := splitIntervals(*root, block.first)
+	// The interval before the network when it overlaps the network, keep the part before it
+	if last := lastInterval(l); last != nil && !last.block.last.less(block.first) {
+		l, last = popLastInterval(l)
+		removed = append(removed, last.block)
+		added = append(added, cidrBlockValue{last.block.first, block.first.subOne()})
+	}
+	// The intervals starting inside the network
+	m, r := splitIntervalsAfter(r, block.last)
+	removed = appendIntervals(removed, m)
+	// Keep the part after the network of the last overlapping interval
+	if n := len(removed); n > 0 && block.last.less(removed[n-1].last) {
+		added = append(added, cidrBlockValue{block.last.addOne(), removed[n-1].last})
+	}
+	for _, block := range added {
+		l = joinIntervals(l, s.newNode(block))
+	}
+	*root = joinIntervals(l, r)
+
+	delta := newSetDelta(removed, added, width)
+	s.notify(delta)
+	return delta, nil
+}
+
+// AddCIDR adds a CIDR block to the set and returns the change of the minimal CIDR cover.
+func (s *MutableSet) AddCIDR(cidr string) (SetDelta, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return SetDelta{}, err
+	}
+	return s.Add(network)
+}
+
+// RemoveCIDR removes a CIDR block from the set and returns the change of the minimal CIDR cover.
+func (s *MutableSet) RemoveCIDR(cidr string) (SetDelta, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return SetDelta{}, err
+	}
+	return s.Remove(network)
+}
+
+// Contains reports whether the IP address is in the set.
+func (s *MutableSet) Contains(ip net.IP) bool {
+	addr, width := ipToUInt128(ip)
+	t := s.root4
+	switch width {
+	case widthUInt128:
+		t = s.root6
+	case 0:
+		return false
+	}
+
+	// Find the last interval starting at or before the address
+	var found *intervalNode
+	for t != nil {
+		if addr.less(t.block.first) {
+			t = t.left
+		} else {
+			found = t
+			t = t.right
+		}
+	}
+	return found != nil && !found.block.last.less(addr)
+}
+
+// IPNets returns the minimal CIDR cover of the set, IPv4 before IPv6.
+func (s *MutableSet) IPNets() []*net.IPNet {
+	nets := appendIntervalIPNets(nil, appendIntervals(nil, s.root4), widthUInt32)
+	return appendIntervalIPNets(nets, appendIntervals(nil, s.root6), widthUInt128)
+}
+
+// CIDRs returns the minimal CIDR cover of the set, IPv4 before IPv6.
+func (s *MutableSet) CIDRs() []string {
+	return ipNets(s.IPNets()).toCIDRs()
+}
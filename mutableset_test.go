@@ -0,0 +1,168 @@
+// go test -v -run="TestMutableSet"
+
+package cidrman
+
+import (
+	"math/rand"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMutableSet(t *testing.T) {
+	type Update struct {
+		Remove  bool
+		CIDR    string
+		Added   []string
+		Removed []string
+	}
+	type TestCase struct {
+		Updates []Update
+		Output  []string
+	}
+
+	testCases := []TestCase{
+		{
+			Updates: []Update{
+				{false, "192.0.2.0/25", []string{"192.0.2.0/25"}, nil},
+				{false, "192.0.2.128/25", []string{"192.0.2.0/24"}, []string{"192.0.2.0/25"}},
+				{false, "192.0.2.7/32", nil, nil},
+				{true, "192.0.2.64/26", []string{"192.0.2.0/26", "192.0.2.128/25"}, []string{"192.0.2.0/24"}},
+				{true, "198.51.100.0/24", nil, nil},
+			},
+			Output: []string{
+				"192.0.2.0/26",
+				"192.0.2.128/25",
+			},
+		},
+		{
+			// Joining several intervals, and splitting them again
+			Updates: []Update{
+				{false, "10.0.1.0/24", []string{"10.0.1.0/24"}, nil},
+				{false, "10.0.4.0/24", []string{"10.0.4.0/24"}, nil},
+				{false, "10.0.2.0/24", []string{"10.0.2.0/24"}, nil},
+				{false, "10.0.0.0/22", []string{"10.0.0.0/22"}, []string{"10.0.1.0/24", "10.0.2.0/24"}},
+				{false, "10.0.5.0/24", []string{"10.0.4.0/23"}, []string{"10.0.4.0/24"}},
+				{true, "10.0.2.0/23", []string{"10.0.0.0/23"}, []string{"10.0.0.0/22"}},
+			},
+			Output: []string{
+				"10.0.0.0/23",
+				"10.0.4.0/23",
+			},
+		},
+		{
+			// Edges of the address space
+			Updates: []Update{
+				{false, "255.255.255.255/32", []string{"255.255.255.255/32"}, nil},
+				{false, "0.0.0.0/1", []string{"0.0.0.0/1"}, nil},
+				{false, "128.0.0.0/1", []string{"0.0.0.0/0"}, []string{"0.0.0.0/1", "255.255.255.255/32"}},
+				{false, "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127", []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127"}, nil},
+				{false, "::/0", []string{"::/0"}, []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127"}},
+				{true, "::/1", []string{"8000::/1"}, []string{"::/0"}},
+			},
+			Output: []string{
+				"0.0.0.0/0",
+				"8000::/1",
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		s := NewMutableSet()
+		var notified []SetDelta
+		s.Subscribe(func(delta SetDelta) {
+			notified = append(notified, delta)
+		})
+		changes := 0
+		for _, update := range testCase.Updates {
+			var delta SetDelta
+			var err error
+			if update.Remove {
+				delta, err = s.RemoveCIDR(update.CIDR)
+			} else {
+				delta, err = s.AddCIDR(update.CIDR)
+			}
+			if err != nil {
+				t.Errorf("Update(%d, %s) failed: %s", i, update.CIDR, err.Error())
+				continue
+			}
+			if len(update.Added) > 0 || len(update.Removed) > 0 {
+				changes++
+			}
+			added, removed := ipNets(delta.Added).toCIDRs(), ipNets(delta.Removed).toCIDRs()
+			if !reflect.DeepEqual(update.Added, added) || !reflect.DeepEqual(update.Removed, removed) {
+				t.Errorf("Update(%d, %s) expected: +%#v -%#v, got: +%#v -%#v", i, update.CIDR, update.Added, update.Removed, added, removed)
+			}
+		}
+		if len(notified) != changes {
+			t.Errorf("Subscribe(%d) expected %d notifications, got: %d", i, changes, len(notified))
+		}
+		if output := s.CIDRs(); !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("CIDRs(%d) expected: %#v, got: %#v", i, testCase.Output, output)
+		}
+	}
+
+	if _, err := NewMutableSet().AddCIDR("10.0.0.0/33"); err == nil {
+		t.Errorf("AddCIDR() expected error")
+	}
+}
+
+func TestMutableSetRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	adds := randomIPNets(500, 8, 1)
+	removes := randomIPNets(500, 10, 2)
+
+	// Compare with recomputing the cover from scratch, and with applying the deltas to the previous cover
+	s := NewMutableSet()
+	var expected []*net.IPNet
+	cover := make(map[string]bool)
+	for i := 0; i < 500; i++ {
+		var delta SetDelta
+		var err error
+		if r.Intn(3) == 0 {
+			delta, err = s.Remove(removes[i])
+			expected, _ = RemoveIPNets(expected, []*net.IPNet{removes[i]})
+		} else {
+			delta, err = s.Add(adds[i])
+			expected, _ = MergeIPNets(append(expected, adds[i]))
+		}
+		if err != nil {
+			t.Fatalf("Update(%d) failed: %s", i, err.Error())
+		}
+		for _, net := range delta.Removed {
+			if !cover[net.String()] {
+				t.Fatalf("Update(%d) removed %s, not in the cover", i, net.String())
+			}
+			delete(cover, net.String())
+		}
+		for _, net := range delta.Added {
+			cover[net.String()] = true
+		}
+
+		output := s.CIDRs()
+		if !reflect.DeepEqual(ipNets(expected).toCIDRs(), output) {
+			t.Fatalf("Update(%d) expected: %#v, got: %#v", i, ipNets(expected).toCIDRs(), output)
+		}
+		var covered []string
+		for cidr := range cover {
+			covered = append(covered, cidr)
+		}
+		sort.Strings(covered)
+		sort.Strings(output)
+		if !reflect.DeepEqual(output, covered) {
+			t.Fatalf("Update(%d) deltas give: %#v, expected: %#v", i, covered, output)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		ip := randomIPNets(1, 32, int64(i))[0].IP
+		contained := false
+		for _, net := range expected {
+			contained = contained || net.Contains(ip)
+		}
+		if s.Contains(ip) != contained {
+			t.Errorf("Contains(%s) expected: %v", ip, contained)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package cidrman
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Options controls how the set operations parse their input and return their output.
+// The zero value is the behaviour of MergeIPNets, MergeCIDRs and the other functions without options.
+type Options struct {
+	// Strict rejects input that net.ParseCIDR and net.ParseIP would normalise or accept
+	// ambiguously: host bits set, like 192.168.1.77/24, zoned addresses, like fe80::1%eth0,
+	// leading zeros in IPv4 octets and prefix lengths, like 010.0.0.0/08, and a mix of IPv4
+	// and IPv6 within one call. The errors are of type *ParseError.
+	Strict bool
+	// EmptySubsetUnchanged returns the networks unchanged from a subset with an empty subset list,
+	// instead of an empty result.
+	EmptySubsetUnchanged bool
+	// Order is the order of the output, by default IPv4 before IPv6, each by address.
+	Order SortOrder
+	// Family drops the networks of the other address family from the input of merge, remove and subset.
+	Family Family
+	// IPv4Mapped is the policy for IPv4-mapped IPv6 networks, like ::ffff:192.0.2.0/120.
+	IPv4Mapped IPv4MappedPolicy
+	// Ranges returns the output of the string functions as address ranges, like
+	// 192.0.2.0-192.0.3.255, instead of CIDR blocks. Adjacent CIDR blocks are joined.
+	Ranges bool
+}
+
+// Option changes one of the Options of Merge, Remove and Subset.
+// Example:
+//     merged, err := Merge(nets, WithFamily(FamilyIPv6), WithOrder(SortByPrefixLength))
+type Option func(*Options)
+
+// Family selects an address family.
+type Family int
+
+const (
+	// FamilyAll is both IPv4 and IPv6.
+	FamilyAll Family = iota
+	// FamilyIPv4 is IPv4 only.
+	FamilyIPv4
+	// FamilyIPv6 is IPv6 only.
+	FamilyIPv6
+)
+
+// IPv4MappedPolicy selects how IPv4-mapped IPv6 networks, inside ::ffff:0:0/96, are handled.
+type IPv4MappedPolicy int
+
+const (
+	// IPv4MappedAsIPv4 handles ::ffff:192.0.2.0/120 as 192.0.2.0/24, the default of MergeIPNets,
+	// RemoveIPNets and SubsetIPNets. Networks shorter than /96, like ::ffff:0:0/80, stay IPv6.
+	IPv4MappedAsIPv4 IPv4MappedPolicy = iota
+	// IPv4MappedAsIPv6 handles ::ffff:192.0.2.0/120 as an IPv6 network.
+	IPv4MappedAsIPv6
+	// IPv4MappedReject returns an error for IPv4-mapped IPv6 networks.
+	IPv4MappedReject
+)
+
+// WithStrict sets Options.Strict.
+func WithStrict() Option {
+	return func(o *Options) {
+		o.Strict = true
+	}
+}
+
+// WithEmptySubsetUnchanged sets Options.EmptySubsetUnchanged.
+func WithEmptySubsetUnchanged() Option {
+	return func(o *Options) {
+		o.EmptySubsetUnchanged = true
+	}
+}
+
+// WithOrder sets Options.Order.
+func WithOrder(order SortOrder) Option {
+	return func(o *Options) {
+		o.Order = order
+	}
+}
+
+// WithFamily sets Options.Family.
+func WithFamily(family Family) Option {
+	return func(o *Options) {
+		o.Family = family
+	}
+}
+
+// WithIPv4Mapped sets Options.IPv4Mapped.
+func WithIPv4Mapped(policy IPv4MappedPolicy) Option {
+	return func(o *Options) {
+		o.IPv4Mapped = policy
+	}
+}
+
+// WithRanges sets Options.Ranges.
+func WithRanges() Option {
+	return func(o *Options) {
+		o.Ranges = true
+	}
+}
+
+// newOptions returns the Options with the options applied.
+func newOptions(opts []Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ipv4Network returns the IPv4 address and mask of a network, or a nil address for an IPv6
+// network. An IPv4-mapped IPv6 network with a 16-byte mask, as returned by net.ParseCIDR for
+// ::ffff:192.0.2.0/120, is the IPv4 network of the last 32 bits of its mask from /96, like
+// 192.0.2.0/24, and an IPv6 network shorter than /96. Mapped reports an IPv4-mapped IPv6 network.
+func ipv4Network(network *net.IPNet) (ip4 net.IP, mask net.IPMask, mapped bool) {
+	ip4 = network.IP.To4()
+	mask = network.Mask
+	if ip4 == nil || len(network.IP) != net.IPv6len || len(mask) != net.IPv6len {
+		return ip4, mask, false
+	}
+	if prefix, _ := mask.Size(); prefix < 96 {
+		return nil, mask, true
+	}
+	return ip4, mask[12:], true
+}
+
+// splitFamilies splits a list of mixed IP networks into IPv4 and IPv6 blocks,
+// dropping the networks filtered out by the options.
+func (o Options) splitFamilies(nets []*net.IPNet) (cidrBlock4s, cidrBlock6s, error) {
+	var block4s cidrBlock4s
+	var block6s cidrBlock6s
+	for _, network := range nets {
+		ip4, mask, mapped := ipv4Network(network)
+		if mapped {
+			switch o.IPv4Mapped {
+			case IPv4MappedReject:
+				return nil, nil, fmt.Errorf("IPv4-mapped IPv6 network: %v", network)
+			case IPv4MappedAsIPv6:
+				ip4, mask = nil, network.Mask
+			}
+		}
+		if ip4 != nil {
+			if o.Family != FamilyIPv6 {
+				block4s = append(block4s, newBlock4(ip4, mask))
+			}
+		} else {
+			ip6 := network.IP.To16()
+			if ip6 == nil {
+				return nil, nil, fmt.Errorf("Invalid IP address: %v", network.IP)
+			}
+			if o.Family != FamilyIPv4 {
+				block6s = append(block6s, newBlock6(ip6, mask))
+			}
+		}
+	}
+	return block4s, block6s, nil
+}
+
+// unchanged reports whether the options leave the networks of a set operation as they are,
+// so that nothing to remove, or an empty subset with EmptySubsetUnchanged, returns the input.
+func (o Options) unchanged() bool {
+	return o.Family == FamilyAll && o.IPv4Mapped == IPv4MappedAsIPv4 && !o.Ranges && o.Order == SortByAddress
+}
+
+// sorted returns the output of a set operation, IPv4 before IPv6, each by address, in the order of the options.
+func (o Options) sorted(nets []*net.IPNet) ([]*net.IPNet, error) {
+	if o.Order == SortByAddress {
+		return nets, nil
+	}
+	return SortIPNets(nets, o.Order)
+}
+
+// toStrings returns the output of a set operation, IPv4 before IPv6, each by address, as strings
+// in the form and order of the options. Address ranges are only ordered by address family.
+func (o Options) toStrings(nets []*net.IPNet) ([]string, error) {
+	if !o.Ranges {
+		sorted, err := o.sorted(nets)
+		if err != nil {
+			return nil, err
+		}
+		return ipNets(sorted).toCIDRs(), nil
+	}
+
+	var ranges4, ranges6 []string
+	for i := 0; i < len(nets); {
+		_, width := ipToUInt128(nets[i].IP)
+		if width == 0 {
+			return nil, fmt.Errorf("Invalid IP address: %v", nets[i].IP)
+		}
+		start := nets[i].IP
+
+		// Join the adjacent CIDR blocks
+		var last uint128
+		var end net.IP
+		for ; i < len(nets); i++ {
+			addr, w := ipToUInt128(nets[i].IP)
+			if end != nil && (w != width || last.isMax() || last.addOne() != addr) {
+				break
+			}
+			prefix, _ := nets[i].Mask.Size()
+			last = addr.or(hostmask128(width - uint(prefix)))
+			end = uint128ToIP(last, width)
+		}
+		if width == widthUInt32 {
+			ranges4 = append(ranges4, start.String()+"-"+end.String())
+		} else {
+			ranges6 = append(ranges6, start.String()+"-"+end.String())
+		}
+	}
+
+	if o.Order&SortIPv6First != 0 {
+		return append(ranges6, ranges4...), nil
+	}
+	return append(ranges4, ranges6...), nil
+}
+
+// ParseErrorKind is the kind of input rejected in strict mode.
+type ParseErrorKind int
+
+const (
+	// ParseInvalid is input that is not a CIDR block or IP address at all.
+	ParseInvalid ParseErrorKind = iota
+	// ParseHostBits is a CIDR block with host bits set, like 192.168.1.77/24.
+	ParseHostBits
+	// ParseZone is an IPv6 address with a zone, like fe80::1%eth0.
+	ParseZone
+	// ParseLeadingZeros is an IPv4 octet or prefix length with leading zeros, like 010.0.0.0/08.
+	ParseLeadingZeros
+	// ParseMixedFamilies is the first input of the second address family in a call with both IPv4 and IPv6.
+	ParseMixedFamilies
+)
+
+// String returns the name of the kind.
+func (k ParseErrorKind) String() string {
+	switch k {
+	case ParseInvalid:
+		return "invalid"
+	case ParseHostBits:
+		return "host-bits"
+	case ParseZone:
+		return "zone"
+	case ParseLeadingZeros:
+		return "leading-zeros"
+	case ParseMixedFamilies:
+		return "mixed-families"
+	}
+	return fmt.Sprintf("ParseErrorKind(%d)", int(k))
+}
+
+// ParseError is the error returned in strict mode for input that is not canonical.
+// Example:
+//     _, err := MergeCIDRsWithOptions([]string{"192.168.1.77/24"}, Options{Strict: true})
+//     if perr, ok := err.(*ParseError); ok && perr.Kind == ParseHostBits {
+//         ...
+//     }
+type ParseError struct {
+	Kind ParseErrorKind
+	// Input is the rejected CIDR block or IP address, as written.
+	Input string
+	// Err is the error of net.ParseCIDR or net.ParseIP for ParseInvalid, nil otherwise.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	switch e.Kind {
+	case ParseInvalid:
+		if e.Err != nil {
+			return e.Err.Error()
+		}
+		return fmt.Sprintf("Invalid IP address: %s", e.Input)
+	case ParseHostBits:
+		return fmt.Sprintf("Host bits set: %s", e.Input)
+	case ParseZone:
+		return fmt.Sprintf("Zoned IP address: %s", e.Input)
+	case ParseLeadingZeros:
+		return fmt.Sprintf("Leading zeros: %s", e.Input)
+	case ParseMixedFamilies:
+		return fmt.Sprintf("Mixed IPv4 and IPv6: %s", e.Input)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Input)
+}
+
+// hasLeadingZeros reports whether a decimal number has leading zeros, like 08.
+func hasLeadingZeros(s string) bool {
+	return len(s) > 1 && s[0] == '0'
+}
+
+// checkAddress returns a *ParseError for a zone or leading zeros in the IPv4 octets of an address.
+// The IPv4 octets are at the end of an IPv6 address, like ::ffff:192.0.2.1.
+func checkAddress(s, input string) error {
+	if strings.IndexByte(s, '%') >= 0 {
+		return &ParseError{Kind: ParseZone, Input: input}
+	}
+	if strings.IndexByte(s, '.') >= 0 {
+		for _, octet := range strings.Split(s[strings.LastIndexByte(s, ':')+1:], ".") {
+			if hasLeadingZeros(octet) {
+				return &ParseError{Kind: ParseLeadingZeros, Input: input}
+			}
+		}
+	}
+	return nil
+}
+
+// parser parses the input of one call, remembering the first address family in strict mode.
+type parser struct {
+	opts  Options
+	width uint
+}
+
+// checkFamily returns a *ParseError if the IP address is not of the first address family seen.
+func (p *parser) checkFamily(ip net.IP, input string) error {
+	_, width := ipToUInt128(ip)
+	if p.width == 0 {
+		p.width = width
+	}
+	if width != p.width {
+		return &ParseError{Kind: ParseMixedFamilies, Input: input}
+	}
+	return nil
+}
+
+// parseCIDR parses a CIDR block like net.ParseCIDR and returns its network.
+func (p *parser) parseCIDR(cidr string) (*net.IPNet, error) {
+	if !p.opts.Strict {
+		_, network, err := net.ParseCIDR(cidr)
+		return network, err
+	}
+
+	i := strings.IndexByte(cidr, '/')
+	if i >= 0 {
+		if err := checkAddress(cidr[:i], cidr); err != nil {
+			return nil, err
+		}
+		if hasLeadingZeros(cidr[i+1:]) {
+			return nil, &ParseError{Kind: ParseLeadingZeros, Input: cidr}
+		}
+	}
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, &ParseError{Kind: ParseInvalid, Input: cidr, Err: err}
+	}
+	if !ip.Equal(network.IP) {
+		return nil, &ParseError{Kind: ParseHostBits, Input: cidr}
+	}
+	if err := p.checkFamily(network.IP, cidr); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// parseCIDRs parses a list of CIDR blocks.
+func (p *parser) parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		network, err := p.parseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// parseIP parses an IP address like net.ParseIP.
+func (p *parser) parseIP(s string) (net.IP, error) {
+	if p.opts.Strict {
+		if err := checkAddress(s, s); err != nil {
+			return nil, err
+		}
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		if p.opts.Strict {
+			return nil, &ParseError{Kind: ParseInvalid, Input: s}
+		}
+		return nil, fmt.Errorf("Invalid IP address: %s", s)
+	}
+	if p.opts.Strict {
+		if err := p.checkFamily(ip, s); err != nil {
+			return nil, err
+		}
+	}
+	return ip, nil
+}
+
+// MergeCIDRsWithOptions is MergeCIDRs with options.
+func MergeCIDRsWithOptions(cidrs []string, opts Options) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+	if len(cidrs) == 0 {
+		return make([]string, 0), nil
+	}
+
+	p := parser{opts: opts}
+	networks, err := p.parseCIDRs(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	mergedNets, err := merge(networks, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts.toStrings(mergedNets)
+}
+
+// RemoveCIDRsWithOptions is RemoveCIDRs with options.
+// In strict mode the CIDR blocks are checked even when there is nothing to remove.
+func RemoveCIDRsWithOptions(cidrs, removes []string, opts Options) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+	if len(cidrs) == 0 {
+		return make([]string, 0), nil
+	}
+	if len(removes) == 0 && !opts.Strict && opts.unchanged() {
+		return cidrs, nil
+	}
+
+	p := parser{opts: opts}
+	networks, err := p.parseCIDRs(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	if len(removes) == 0 && opts.unchanged() {
+		return cidrs, nil
+	}
+	rmnets, err := p.parseCIDRs(removes)
+	if err != nil {
+		return nil, err
+	}
+
+	newNets, err := remove(networks, rmnets, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Handle the situation where all cidrs were removed
+	if len(newNets) == 0 {
+		return make([]string, 0), nil
+	}
+
+	return opts.toStrings(newNets)
+}
+
+// SubsetCIDRsWithOptions is SubsetCIDRs with options.
+// In strict mode the CIDR blocks are checked even when the subset is empty.
+func SubsetCIDRsWithOptions(cidrs, subsets []string, opts Options) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+	if len(cidrs) == 0 {
+		return make([]string, 0), nil
+	}
+	if len(subsets) == 0 && !opts.Strict && opts.unchanged() {
+		if opts.EmptySubsetUnchanged {
+			return cidrs, nil
+		}
+		// With empty subset, return empty result
+		return make([]string, 0), nil
+	}
+
+	p := parser{opts: opts}
+	networks, err := p.parseCIDRs(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	if len(subsets) == 0 && opts.unchanged() {
+		if opts.EmptySubsetUnchanged {
+			return cidrs, nil
+		}
+		return make([]string, 0), nil
+	}
+	subsetnets, err := p.parseCIDRs(subsets)
+	if err != nil {
+		return nil, err
+	}
+
+	newNets, err := subset(networks, subsetnets, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Handle the situation where no cidrs overlapped
+	if len(newNets) == 0 {
+		return make([]string, 0), nil
+	}
+
+	return opts.toStrings(newNets)
+}
+
+// IPRangeToCIDRsWithOptions is IPRangeToCIDRs with options.
+func IPRangeToCIDRsWithOptions(start, end string, opts Options) ([]string, error) {
+	p := parser{opts: opts}
+	ipStart, err := p.parseIP(start)
+	if err != nil {
+		return nil, err
+	}
+	ipEnd, err := p.parseIP(end)
+	if err != nil {
+		return nil, err
+	}
+
+	nets, err := IPRangeToIPNets(ipStart, ipEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts.toStrings(nets)
+}
@@ -0,0 +1,482 @@
+// go test -v -run="TestStrict|TestMergeOptions|TestSetOptionsCIDRs"
+
+package cidrman
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestStrictMergeCIDRs(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Output []string
+		Kind   ParseErrorKind
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input:  []string{"192.168.1.0/25", "192.168.1.128/25"},
+			Output: []string{"192.168.1.0/24"},
+			Error:  false,
+		},
+		{
+			Input:  []string{"2001:db8::/33", "2001:0db8:8000::/33"},
+			Output: []string{"2001:db8::/32"},
+			Error:  false,
+		},
+		{
+			Input: []string{"192.168.1.77/24"},
+			Kind:  ParseHostBits,
+			Error: true,
+		},
+		{
+			Input: []string{"fe80::%eth0/64"},
+			Kind:  ParseZone,
+			Error: true,
+		},
+		{
+			Input: []string{"192.168.010.0/24"},
+			Kind:  ParseLeadingZeros,
+			Error: true,
+		},
+		{
+			Input: []string{"192.168.1.0/024"},
+			Kind:  ParseLeadingZeros,
+			Error: true,
+		},
+		{
+			Input: []string{"::ffff:192.168.001.0/120"},
+			Kind:  ParseLeadingZeros,
+			Error: true,
+		},
+		{
+			Input: []string{"192.168.1.0/24", "2001:db8::/32"},
+			Kind:  ParseMixedFamilies,
+			Error: true,
+		},
+		{
+			Input: []string{"192.168.1.0/33"},
+			Kind:  ParseInvalid,
+			Error: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := MergeCIDRsWithOptions(testCase.Input, Options{Strict: true})
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("MergeCIDRsWithOptions(%#v) failed: %s", testCase.Input, err.Error())
+			} else if perr, ok := err.(*ParseError); !ok || perr.Kind != testCase.Kind {
+				t.Errorf("MergeCIDRsWithOptions(%#v) expected %s error, got: %#v", testCase.Input, testCase.Kind, err)
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("MergeCIDRsWithOptions(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("MergeCIDRsWithOptions(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+
+	// Without strict mode the input is normalised
+	output, err := MergeCIDRsWithOptions([]string{"192.168.1.77/24"}, Options{})
+	if err != nil || !reflect.DeepEqual(output, []string{"192.168.1.0/24"}) {
+		t.Errorf("MergeCIDRsWithOptions(192.168.1.77/24) expected: 192.168.1.0/24, got: %#v, %v", output, err)
+	}
+}
+
+func TestStrictRemoveSubsetRange(t *testing.T) {
+	type TestCase struct {
+		Name  string
+		Call  func() ([]string, error)
+		Kind  ParseErrorKind
+		Error bool
+	}
+
+	strict := Options{Strict: true}
+	testCases := []TestCase{
+		{
+			Name: "RemoveCIDRsWithOptions",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.0.0/8"}, []string{"10.1.0.0/16"}, strict)
+			},
+			Error: false,
+		},
+		{
+			Name: "RemoveCIDRsWithOptions host bits",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.0.0/8"}, []string{"10.1.0.1/16"}, strict)
+			},
+			Kind:  ParseHostBits,
+			Error: true,
+		},
+		{
+			Name: "RemoveCIDRsWithOptions empty removes",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.0.1/8"}, nil, strict)
+			},
+			Kind:  ParseHostBits,
+			Error: true,
+		},
+		{
+			Name: "RemoveCIDRsWithOptions mixed families",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.0.0/8"}, []string{"2001:db8::/32"}, strict)
+			},
+			Kind:  ParseMixedFamilies,
+			Error: true,
+		},
+		{
+			Name: "SubsetCIDRsWithOptions",
+			Call: func() ([]string, error) {
+				return SubsetCIDRsWithOptions([]string{"10.0.0.0/8"}, []string{"10.1.0.0/16"}, strict)
+			},
+			Error: false,
+		},
+		{
+			Name: "SubsetCIDRsWithOptions leading zeros",
+			Call: func() ([]string, error) {
+				return SubsetCIDRsWithOptions([]string{"10.0.0.0/8"}, []string{"10.01.0.0/16"}, strict)
+			},
+			Kind:  ParseLeadingZeros,
+			Error: true,
+		},
+		{
+			Name: "IPRangeToCIDRsWithOptions",
+			Call: func() ([]string, error) {
+				return IPRangeToCIDRsWithOptions("10.0.0.0", "10.0.0.255", strict)
+			},
+			Error: false,
+		},
+		{
+			Name: "IPRangeToCIDRsWithOptions zone",
+			Call: func() ([]string, error) {
+				return IPRangeToCIDRsWithOptions("fe80::1%eth0", "fe80::ff", strict)
+			},
+			Kind:  ParseZone,
+			Error: true,
+		},
+		{
+			Name: "IPRangeToCIDRsWithOptions mixed families",
+			Call: func() ([]string, error) {
+				return IPRangeToCIDRsWithOptions("10.0.0.0", "2001:db8::", strict)
+			},
+			Kind:  ParseMixedFamilies,
+			Error: true,
+		},
+		{
+			Name: "IPRangeToCIDRsWithOptions invalid",
+			Call: func() ([]string, error) {
+				return IPRangeToCIDRsWithOptions("10.0.0.256", "10.0.1.0", strict)
+			},
+			Kind:  ParseInvalid,
+			Error: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := testCase.Call()
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("%s failed: %s", testCase.Name, err.Error())
+			} else if perr, ok := err.(*ParseError); !ok || perr.Kind != testCase.Kind {
+				t.Errorf("%s expected %s error, got: %#v", testCase.Name, testCase.Kind, err)
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("%s expected error, got: %#v", testCase.Name, output)
+		}
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	type TestCase struct {
+		Input   []string
+		Options []Option
+		Output  []string
+		Error   bool
+	}
+
+	input := []string{
+		"2001:db8::/33",
+		"192.0.2.0/25",
+		"10.0.0.0/8",
+		"2001:db8:8000::/33",
+		"192.0.2.128/25",
+		"::ffff:198.51.100.0/120",
+	}
+
+	testCases := []TestCase{
+		{
+			Input:   input,
+			Options: nil,
+			Output:  []string{"10.0.0.0/8", "192.0.2.0/24", "198.51.100.0/24", "2001:db8::/32"},
+			Error:   false,
+		},
+		{
+			Input:   input,
+			Options: []Option{WithOrder(SortByPrefixLength | SortIPv6First)},
+			Output:  []string{"2001:db8::/32", "10.0.0.0/8", "192.0.2.0/24", "198.51.100.0/24"},
+			Error:   false,
+		},
+		{
+			Input:   input,
+			Options: []Option{WithFamily(FamilyIPv4)},
+			Output:  []string{"10.0.0.0/8", "192.0.2.0/24", "198.51.100.0/24"},
+			Error:   false,
+		},
+		{
+			Input:   input,
+			Options: []Option{WithFamily(FamilyIPv6)},
+			Output:  []string{"2001:db8::/32"},
+			Error:   false,
+		},
+		{
+			// net.IPNet.String prints ::ffff:198.51.100.0/120 as IPv4
+			Input:   input,
+			Options: []Option{WithFamily(FamilyIPv6), WithIPv4Mapped(IPv4MappedAsIPv6)},
+			Output:  []string{"198.51.100.0/24", "2001:db8::/32"},
+			Error:   false,
+		},
+		{
+			Input:   []string{"2001:db8::/32"},
+			Options: []Option{WithFamily(FamilyIPv4)},
+			Output:  []string{},
+			Error:   false,
+		},
+		{
+			Input:   input,
+			Options: []Option{WithIPv4Mapped(IPv4MappedReject)},
+			Output:  nil,
+			Error:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		var nets []*net.IPNet
+		for _, cidr := range testCase.Input {
+			_, network, _ := net.ParseCIDR(cidr)
+			nets = append(nets, network)
+		}
+		merged, err := Merge(nets, testCase.Options...)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("Merge(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("Merge(%#v) expected error, got: %#v", testCase.Input, merged)
+			continue
+		}
+		output := ipNets(merged).toCIDRs()
+		if output == nil {
+			output = []string{}
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("Merge(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
+
+func TestRemoveSubsetOptions(t *testing.T) {
+	type TestCase struct {
+		Name    string
+		Call    func(nets, others []*net.IPNet, opts ...Option) ([]*net.IPNet, error)
+		Others  []string
+		Options []Option
+		Output  []string
+		Error   bool
+	}
+
+	input := []string{
+		"2001:db8::/33",
+		"10.0.0.0/8",
+		"2001:db8:8000::/33",
+		"::ffff:198.51.100.0/120",
+	}
+
+	testCases := []TestCase{
+		{
+			Name:    "Remove",
+			Call:    Remove,
+			Others:  nil,
+			Options: nil,
+			Output:  []string{"2001:db8::/33", "10.0.0.0/8", "2001:db8:8000::/33", "198.51.100.0/24"},
+			Error:   false,
+		},
+		{
+			Name:    "Remove",
+			Call:    Remove,
+			Others:  nil,
+			Options: []Option{WithFamily(FamilyIPv4)},
+			Output:  []string{"10.0.0.0/8", "198.51.100.0/24"},
+			Error:   false,
+		},
+		{
+			Name:    "Remove",
+			Call:    Remove,
+			Others:  []string{},
+			Options: []Option{WithOrder(SortIPv6First)},
+			Output:  []string{"2001:db8::/32", "10.0.0.0/8", "198.51.100.0/24"},
+			Error:   false,
+		},
+		{
+			Name:    "Remove",
+			Call:    Remove,
+			Others:  nil,
+			Options: []Option{WithIPv4Mapped(IPv4MappedReject)},
+			Output:  nil,
+			Error:   true,
+		},
+		{
+			Name:    "Subset",
+			Call:    Subset,
+			Others:  nil,
+			Options: []Option{WithFamily(FamilyIPv6), WithEmptySubsetUnchanged()},
+			Output:  []string{"2001:db8::/32"},
+			Error:   false,
+		},
+		{
+			Name:    "Subset",
+			Call:    Subset,
+			Others:  []string{},
+			Options: []Option{WithFamily(FamilyIPv6)},
+			Output:  []string{},
+			Error:   false,
+		},
+		{
+			Name:    "Subset",
+			Call:    Subset,
+			Others:  nil,
+			Options: []Option{WithIPv4Mapped(IPv4MappedReject), WithEmptySubsetUnchanged()},
+			Output:  nil,
+			Error:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		var nets, others []*net.IPNet
+		for _, cidr := range input {
+			_, network, _ := net.ParseCIDR(cidr)
+			nets = append(nets, network)
+		}
+		if testCase.Others != nil {
+			others = []*net.IPNet{}
+		}
+		result, err := testCase.Call(nets, others, testCase.Options...)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("%s(%#v, %#v) failed: %s", testCase.Name, input, testCase.Others, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("%s(%#v, %#v) expected error, got: %#v", testCase.Name, input, testCase.Others, result)
+			continue
+		}
+		output := ipNets(result).toCIDRs()
+		if output == nil {
+			output = []string{}
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("%s(%#v, %#v) expected: %#v, got: %#v", testCase.Name, input, testCase.Others, testCase.Output, output)
+		}
+	}
+}
+
+func TestSetOptionsCIDRs(t *testing.T) {
+	type TestCase struct {
+		Name   string
+		Call   func() ([]string, error)
+		Output []string
+	}
+
+	testCases := []TestCase{
+		{
+			Name: "SubsetCIDRsWithOptions empty subset",
+			Call: func() ([]string, error) {
+				return SubsetCIDRsWithOptions([]string{"10.0.0.0/8"}, nil, Options{})
+			},
+			Output: []string{},
+		},
+		{
+			Name: "SubsetCIDRsWithOptions empty subset unchanged",
+			Call: func() ([]string, error) {
+				return SubsetCIDRsWithOptions([]string{"10.0.0.0/8"}, nil, Options{EmptySubsetUnchanged: true})
+			},
+			Output: []string{"10.0.0.0/8"},
+		},
+		{
+			Name: "MergeCIDRsWithOptions ranges",
+			Call: func() ([]string, error) {
+				return MergeCIDRsWithOptions([]string{"10.0.0.0/24", "2001:db8::/64", "10.0.1.0/25", "192.0.2.1/32", "10.0.1.128/26"}, Options{Ranges: true, Order: SortIPv6First})
+			},
+			Output: []string{"2001:db8::-2001:db8::ffff:ffff:ffff:ffff", "10.0.0.0-10.0.1.191", "192.0.2.1-192.0.2.1"},
+		},
+		{
+			Name: "RemoveCIDRsWithOptions ranges",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.0.0/24"}, []string{"10.0.0.0/32", "10.0.0.255/32"}, Options{Ranges: true})
+			},
+			Output: []string{"10.0.0.1-10.0.0.254"},
+		},
+		{
+			Name: "RemoveCIDRsWithOptions order",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.0.0/30"}, []string{"10.0.0.0/32"}, Options{Order: SortByPrefixLength})
+			},
+			Output: []string{"10.0.0.2/31", "10.0.0.1/32"},
+		},
+		{
+			Name: "RemoveCIDRsWithOptions empty remove ranges",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.1.0/24", "10.0.0.0/24", "2001:db8::/64"}, nil, Options{Ranges: true, Family: FamilyIPv4})
+			},
+			Output: []string{"10.0.0.0-10.0.1.255"},
+		},
+		{
+			Name: "RemoveCIDRsWithOptions empty remove order",
+			Call: func() ([]string, error) {
+				return RemoveCIDRsWithOptions([]string{"10.0.0.0/24", "2001:db8::/64"}, []string{}, Options{Order: SortIPv6First})
+			},
+			Output: []string{"2001:db8::/64", "10.0.0.0/24"},
+		},
+		{
+			Name: "SubsetCIDRsWithOptions empty subset unchanged family",
+			Call: func() ([]string, error) {
+				return SubsetCIDRsWithOptions([]string{"10.0.0.0/8", "2001:db8::/32"}, nil, Options{EmptySubsetUnchanged: true, Family: FamilyIPv6})
+			},
+			Output: []string{"2001:db8::/32"},
+		},
+		{
+			Name: "IPRangeToCIDRsWithOptions ranges",
+			Call: func() ([]string, error) {
+				return IPRangeToCIDRsWithOptions("10.0.0.1", "10.0.0.6", Options{Ranges: true})
+			},
+			Output: []string{"10.0.0.1-10.0.0.6"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := testCase.Call()
+		if err != nil {
+			t.Errorf("%s failed: %s", testCase.Name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("%s expected: %#v, got: %#v", testCase.Name, testCase.Output, output)
+		}
+	}
+}
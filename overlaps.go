@@ -0,0 +1,135 @@
+package cidrman
+
+import (
+	"net"
+	"sort"
+)
+
+// Overlap is a region of addresses that is in more than one of the lists given to FindOverlaps.
+type Overlap struct {
+	// CIDRs are the CIDR blocks of the region.
+	CIDRs []string
+	// Names are the names of the lists the region is in, sorted.
+	Names []string
+}
+
+// overlapRegion is an interval of addresses with the indexes of the lists it is in.
+type overlapRegion struct {
+	block cidrBlockValue
+	lists []int
+}
+
+// sweepOverlaps returns the regions of the edges of one address family that are in more than one list.
+func sweepOverlaps(edges blockEdges) []overlapRegion {
+	sort.Sort(edges)
+
+	// Sweep the edges, keeping the sorted lists active from the address first
+	var regions []overlapRegion
+	var active []int
+	var first uint128
+	done := false
+	closeRegion := func(last uint128) {
+		if len(active) < 2 || done || last.less(first) {
+			return
+		}
+		n := len(regions)
+		if n > 0 && regions[n-1].block.last.addOne() == first && equalInts(regions[n-1].lists, active) {
+			// Adjacent to the previous region, in the same lists
+			regions[n-1].block.last = last
+			return
+		}
+		regions = append(regions, overlapRegion{cidrBlockValue{first, last}, append([]int(nil), active...)})
+	}
+	for _, edge := range edges {
+		i := sort.SearchInts(active, edge.value)
+		if edge.start {
+			if first.less(edge.addr) {
+				closeRegion(edge.addr.subOne())
+			}
+			first, done = edge.addr, false
+			active = append(active, 0)
+			copy(active[i+1:], active[i:])
+			active[i] = edge.value
+			continue
+		}
+		closeRegion(edge.addr)
+		first, done = edge.addr.addOne(), edge.addr.isMax()
+		active = append(active[:i], active[i+1:]...)
+	}
+	return regions
+}
+
+// equalInts reports whether two lists of ints are equal.
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FindOverlaps returns the regions of addresses that are in more than one of the named lists
+// of mixed CIDR blocks, with the names of the lists each region is in, ordered by address,
+// IPv4 before IPv6. Overlaps within a single list are ignored. All lists are checked in a single
+// sweep over the sorted CIDR blocks.
+// Example:
+//     overlaps, err := FindOverlaps(map[string][]string{
+//         "customer-a": {"10.0.0.0/16"},
+//         "customer-b": {"10.0.128.0/17", "192.0.2.0/24"},
+//     })
+//     // [{[10.0.128.0/17] [customer-a customer-b]}]
+func FindOverlaps(lists map[string][]string) ([]Overlap, error) {
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var edge4s, edge6s blockEdges
+	for i, name := range names {
+		var networks []*net.IPNet
+		for _, cidr := range lists[name] {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, err
+			}
+			networks = append(networks, network)
+		}
+		block4s, block6s, err := loadBlockValues(networks, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// Coalesce each list, so that a list does not overlap itself
+		block4s.coalesce()
+		for _, block := range block4s {
+			edge4s = edge4s.appendBlock(block, i)
+		}
+		block6s.coalesce()
+		for _, block := range block6s {
+			edge6s = edge6s.appendBlock(block, i)
+		}
+	}
+
+	var overlaps []Overlap
+	for _, family := range []struct {
+		edges blockEdges
+		width uint
+	}{{edge4s, widthUInt32}, {edge6s, widthUInt128}} {
+		for _, region := range sweepOverlaps(family.edges) {
+			overlap := Overlap{
+				CIDRs: ipNets(appendIntervalIPNets(nil, cidrBlockValues{region.block}, family.width)).toCIDRs(),
+				Names: make([]string, len(region.lists)),
+			}
+			for i, list := range region.lists {
+				overlap.Names[i] = names[list]
+			}
+			overlaps = append(overlaps, overlap)
+		}
+	}
+	return overlaps, nil
+}
@@ -0,0 +1,93 @@
+// go test -v -run="TestFindOverlaps"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindOverlaps(t *testing.T) {
+	type TestCase struct {
+		Input  map[string][]string
+		Output []Overlap
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input: map[string][]string{
+				"a": {"10.0.0.0/16", "10.0.0.0/24"},
+				"b": {"192.0.2.0/24"},
+			},
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input: map[string][]string{
+				"customer-a": {"10.0.0.0/16"},
+				"customer-b": {"10.0.128.0/17", "192.0.2.0/24"},
+			},
+			Output: []Overlap{
+				{CIDRs: []string{"10.0.128.0/17"}, Names: []string{"customer-a", "customer-b"}},
+			},
+			Error: false,
+		},
+		{
+			Input: map[string][]string{
+				"vrf-c": {"10.0.0.0/24", "2001:db8::/32"},
+				"vrf-a": {"10.0.0.0/25", "10.0.0.192/26", "0.0.0.0/0"},
+				"vrf-b": {"10.0.0.64/26", "10.0.0.128/26", "2001:db8:1::/48", "255.255.255.255/32"},
+			},
+			Output: []Overlap{
+				{CIDRs: []string{"10.0.0.0/26"}, Names: []string{"vrf-a", "vrf-c"}},
+				{CIDRs: []string{"10.0.0.64/26", "10.0.0.128/26"}, Names: []string{"vrf-a", "vrf-b", "vrf-c"}},
+				{CIDRs: []string{"10.0.0.192/26"}, Names: []string{"vrf-a", "vrf-c"}},
+				{CIDRs: []string{"255.255.255.255/32"}, Names: []string{"vrf-a", "vrf-b"}},
+				{CIDRs: []string{"2001:db8:1::/48"}, Names: []string{"vrf-b", "vrf-c"}},
+			},
+			Error: false,
+		},
+		{
+			Input: map[string][]string{
+				"a": {"192.0.2.0/24"},
+				"b": {"192.0.2.0/25", "192.0.2.128/25"},
+				"c": {"192.0.2.0/24"},
+			},
+			Output: []Overlap{
+				{CIDRs: []string{"192.0.2.0/24"}, Names: []string{"a", "b", "c"}},
+			},
+			Error: false,
+		},
+		{
+			Input: map[string][]string{
+				"a": {"10.0.0.0/8"},
+				"b": {"10.0.0.0/33"},
+			},
+			Output: nil,
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := FindOverlaps(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("FindOverlaps(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("FindOverlaps(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("FindOverlaps(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
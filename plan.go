@@ -0,0 +1,184 @@
+package cidrman
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// PlanTemplate is a node of a hierarchical address plan template, like a site with its VLANs.
+// Templates are written as YAML or JSON.
+// Example:
+//     name: region
+//     children:
+//       - name: site
+//         prefix: 40
+//         count: 4
+//         children:
+//           - name: vlan
+//             prefix: 64
+//             count: 16
+type PlanTemplate struct {
+	Name string `json:"name"`
+	// Prefix is the prefix length of each subnet, like 64. The prefix of the root is given to PlanAddresses.
+	Prefix int `json:"prefix"`
+	// Count is the number of subnets, 1 if zero.
+	Count    int             `json:"count"`
+	Children []*PlanTemplate `json:"children"`
+}
+
+// PlanEntry is a subnet of an address plan.
+type PlanEntry struct {
+	// Name is the path of names from the root, like "region/site-2/vlan-10".
+	// Subnets of a template with a count above 1 are numbered from 1.
+	Name string
+	CIDR string
+	// Free are the CIDR blocks of the subnet not allocated to children, nil for a leaf.
+	Free []string
+}
+
+// ParsePlanTemplate reads a YAML or JSON address plan template, a template starting with { is JSON.
+// YAML templates are block mappings and sequences of mappings, with plain or quoted scalars.
+// Example:
+//     {"name": "region", "children": [
+//         {"name": "site", "prefix": 40, "count": 4}
+//     ]}
+func ParsePlanTemplate(r io.Reader) (*PlanTemplate, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		template, err := parseYAMLPlanTemplate(data)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid plan template: %s", err)
+		}
+		return template, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var template PlanTemplate
+	if err := decoder.Decode(&template); err != nil {
+		return nil, fmt.Errorf("Invalid plan template: %s", err)
+	}
+	return &template, nil
+}
+
+// planSubnet is an allocated subnet of a parent in an address plan.
+type planSubnet struct {
+	network  *net.IPNet
+	name     string
+	template *PlanTemplate
+}
+
+// planAllocate allocates the children of a template in a subnet and appends the subnet and
+// its children to the entries, depth first and in address order.
+// The children with the largest subnets are allocated first, each in the first free space
+// large enough, so that the subnets stay aligned and packed.
+func planAllocate(entries []PlanEntry, network *net.IPNet, name string, template *PlanTemplate) ([]PlanEntry, error) {
+	ones, bits := network.Mask.Size()
+	entries = append(entries, PlanEntry{Name: name, CIDR: network.String()})
+	if len(template.Children) == 0 {
+		return entries, nil
+	}
+	entry := len(entries) - 1
+
+	children := make([]*PlanTemplate, len(template.Children))
+	for i, child := range template.Children {
+		if child == nil {
+			return nil, fmt.Errorf("Missing plan template: %s child %d", name, i+1)
+		}
+		children[i] = child
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].Prefix < children[j].Prefix
+	})
+
+	var allocated []*net.IPNet
+	var subnets []planSubnet
+	for _, child := range children {
+		if child.Prefix <= ones || child.Prefix > bits {
+			return nil, fmt.Errorf("Invalid plan prefix: %s/%s /%d in %s", name, child.Name, child.Prefix, network)
+		}
+		count := child.Count
+		if count < 0 {
+			return nil, fmt.Errorf("Invalid plan count: %s/%s %d", name, child.Name, count)
+		}
+		if count == 0 {
+			count = 1
+		}
+
+		free, err := RemoveIPNets([]*net.IPNet{network}, allocated)
+		if err != nil {
+			return nil, err
+		}
+		n := 0
+		for _, block := range free {
+			available, err := subnetCount(block, child.Prefix)
+			if err != nil {
+				// Free space smaller than the subnets
+				continue
+			}
+			for i := int64(0); n < count && big.NewInt(i).Cmp(available) < 0; i++ {
+				subnet := subnetAt(block, child.Prefix, big.NewInt(i))
+				childName := name + "/" + child.Name
+				if count > 1 {
+					childName = fmt.Sprintf("%s-%d", childName, n+1)
+				}
+				allocated = append(allocated, subnet)
+				subnets = append(subnets, planSubnet{subnet, childName, child})
+				n++
+			}
+		}
+		if n < count {
+			return nil, fmt.Errorf("Plan does not fit: %s/%s needs %d more /%d subnets in %s", name, child.Name, count-n, child.Prefix, network)
+		}
+	}
+
+	free, err := RemoveIPNets([]*net.IPNet{network}, allocated)
+	if err != nil {
+		return nil, err
+	}
+	entries[entry].Free = ipNets(free).toCIDRs()
+
+	order, err := sortedIndexes(allocated, SortByAddress)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range order {
+		entries, err = planAllocate(entries, subnets[i].network, subnets[i].name, subnets[i].template)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// PlanAddresses allocates the subnets of an address plan template in a root CIDR block and
+// returns the full plan, the root first and every subnet followed by its children in address
+// order. It returns an error if the subnets of the template do not fit.
+// Example:
+//     template, err := ParsePlanTemplate(file)
+//     plan, err := PlanAddresses("2001:db8::/32", template)
+//     for _, entry := range plan {
+//         fmt.Println(entry.CIDR, entry.Name)  // 2001:db8:100::/40 region/site-2
+//     }
+func PlanAddresses(root string, template *PlanTemplate) ([]PlanEntry, error) {
+	if template == nil {
+		return nil, errors.New("Missing plan template")
+	}
+	_, network, err := net.ParseCIDR(root)
+	if err != nil {
+		return nil, err
+	}
+	return planAllocate(nil, network, template.Name, template)
+}
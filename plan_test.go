@@ -0,0 +1,119 @@
+// go test -v -run="TestPlanAddresses"
+
+package cidrman
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanAddresses(t *testing.T) {
+	type TestCase struct {
+		Root     string
+		Template string
+		Output   []PlanEntry
+		Error    bool
+	}
+
+	testCases := []TestCase{
+		{
+			Root:     "2001:db8::/32",
+			Template: `{"name": "region"}`,
+			Output: []PlanEntry{
+				{Name: "region", CIDR: "2001:db8::/32"},
+			},
+			Error: false,
+		},
+		{
+			Root: "2001:db8::/46",
+			Template: `{"name": "region", "children": [
+				{"name": "link", "prefix": 64},
+				{"name": "site", "prefix": 48, "count": 2, "children": [
+					{"name": "vlan", "prefix": 64, "count": 2}
+				]}
+			]}`,
+			Output: []PlanEntry{
+				{Name: "region", CIDR: "2001:db8::/46", Free: []string{"2001:db8:2:1::/64", "2001:db8:2:2::/63", "2001:db8:2:4::/62", "2001:db8:2:8::/61", "2001:db8:2:10::/60", "2001:db8:2:20::/59", "2001:db8:2:40::/58", "2001:db8:2:80::/57", "2001:db8:2:100::/56", "2001:db8:2:200::/55", "2001:db8:2:400::/54", "2001:db8:2:800::/53", "2001:db8:2:1000::/52", "2001:db8:2:2000::/51", "2001:db8:2:4000::/50", "2001:db8:2:8000::/49", "2001:db8:3::/48"}},
+				{Name: "region/site-1", CIDR: "2001:db8::/48", Free: []string{"2001:db8:0:2::/63", "2001:db8:0:4::/62", "2001:db8:0:8::/61", "2001:db8:0:10::/60", "2001:db8:0:20::/59", "2001:db8:0:40::/58", "2001:db8:0:80::/57", "2001:db8:0:100::/56", "2001:db8:0:200::/55", "2001:db8:0:400::/54", "2001:db8:0:800::/53", "2001:db8:0:1000::/52", "2001:db8:0:2000::/51", "2001:db8:0:4000::/50", "2001:db8:0:8000::/49"}},
+				{Name: "region/site-1/vlan-1", CIDR: "2001:db8::/64"},
+				{Name: "region/site-1/vlan-2", CIDR: "2001:db8:0:1::/64"},
+				{Name: "region/site-2", CIDR: "2001:db8:1::/48", Free: []string{"2001:db8:1:2::/63", "2001:db8:1:4::/62", "2001:db8:1:8::/61", "2001:db8:1:10::/60", "2001:db8:1:20::/59", "2001:db8:1:40::/58", "2001:db8:1:80::/57", "2001:db8:1:100::/56", "2001:db8:1:200::/55", "2001:db8:1:400::/54", "2001:db8:1:800::/53", "2001:db8:1:1000::/52", "2001:db8:1:2000::/51", "2001:db8:1:4000::/50", "2001:db8:1:8000::/49"}},
+				{Name: "region/site-2/vlan-1", CIDR: "2001:db8:1::/64"},
+				{Name: "region/site-2/vlan-2", CIDR: "2001:db8:1:1::/64"},
+				{Name: "region/link", CIDR: "2001:db8:2::/64"},
+			},
+			Error: false,
+		},
+		{
+			Root: "192.0.2.0/24",
+			Template: `
+name: office
+children:
+  - name: users
+    prefix: 26
+    count: 2
+`,
+			Output: []PlanEntry{
+				{Name: "office", CIDR: "192.0.2.0/24", Free: []string{"192.0.2.128/25"}},
+				{Name: "office/users-1", CIDR: "192.0.2.0/26"},
+				{Name: "office/users-2", CIDR: "192.0.2.64/26"},
+			},
+			Error: false,
+		},
+		{
+			Root: "192.0.2.0/24",
+			Template: `{"name": "office", "children": [
+				{"name": "users", "prefix": 26, "count": 3},
+				{"name": "servers", "prefix": 26, "count": 2}
+			]}`,
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Root:     "192.0.2.0/24",
+			Template: `{"name": "office", "children": [{"name": "users", "prefix": 24}]}`,
+			Output:   nil,
+			Error:    true,
+		},
+		{
+			Root:     "192.0.2.0/24",
+			Template: `{"name": "office", "size": 24}`,
+			Output:   nil,
+			Error:    true,
+		},
+		{
+			Root:     "192.0.2.0/24",
+			Template: `{"name": "office", "children": [{"name": "users", "prefix": 26}, null]}`,
+			Output:   nil,
+			Error:    true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		template, err := ParsePlanTemplate(strings.NewReader(testCase.Template))
+		var output []PlanEntry
+		if err == nil {
+			output, err = PlanAddresses(testCase.Root, template)
+		}
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("PlanAddresses(%#v, %s) failed: %s", testCase.Root, testCase.Template, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("PlanAddresses(%#v, %s) expected error, got: %#v", testCase.Root, testCase.Template, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("PlanAddresses(%#v, %s) expected: %#v, got: %#v", testCase.Root, testCase.Template, testCase.Output, output)
+		}
+	}
+}
+
+func TestPlanAddressesNil(t *testing.T) {
+	if output, err := PlanAddresses("192.0.2.0/24", nil); err == nil {
+		t.Errorf("PlanAddresses(%#v, nil) expected error, got: %#v", "192.0.2.0/24", output)
+	}
+}
@@ -0,0 +1,197 @@
+// A YAML reader for address plan templates, for the subset of YAML used by templates: block
+// mappings and sequences of mappings with plain or quoted scalars, without a dependency outside
+// the standard library.
+
+package cidrman
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of a YAML plan template, without comment. A line starting a sequence item
+// has the indentation of the text after its "- ".
+type yamlLine struct {
+	number int
+	indent int
+	// dash is the column of the "- " of a sequence item, or -1.
+	dash int
+	text string
+}
+
+// yamlParser parses the lines of a YAML plan template.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// stripYAMLComment removes a comment from a line, a # at the start or after a space, outside quotes.
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// newYAMLParser splits a YAML plan template into lines, skipping blank lines, comments and a
+// leading document marker.
+func newYAMLParser(data []byte) (*yamlParser, error) {
+	p := &yamlParser{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	number := 0
+	for scanner.Scan() {
+		number++
+		raw := strings.TrimRight(stripYAMLComment(scanner.Text()), " \r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || (text == "---" && len(p.lines) == 0) {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tab in indentation", number)
+		}
+
+		line := yamlLine{number: number, indent: len(raw) - len(text), dash: -1, text: text}
+		if text == "-" || strings.HasPrefix(text, "- ") {
+			item := strings.TrimLeft(text[1:], " ")
+			if item == "" {
+				return nil, fmt.Errorf("line %d: empty sequence item", number)
+			}
+			line.dash = line.indent
+			line.indent += len(text) - len(item)
+			line.text = item
+		}
+		p.lines = append(p.lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// yamlScalar returns the value of a plain, single-quoted or double-quoted scalar.
+func yamlScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	}
+	return s, nil
+}
+
+// template parses a mapping at the given indentation into a template.
+func (p *yamlParser) template(indent int) (*PlanTemplate, error) {
+	template := &PlanTemplate{}
+	seen := make(map[string]bool)
+	for start := p.pos; p.pos < len(p.lines); {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.dash >= 0 && p.pos > start) {
+			// End of the mapping, or the next sequence item
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		p.pos++
+
+		colon := strings.Index(line.text, ":")
+		if colon < 0 || (colon+1 < len(line.text) && line.text[colon+1] != ' ') {
+			return nil, fmt.Errorf("line %d: expected key: value", line.number)
+		}
+		key := line.text[:colon]
+		value, err := yamlScalar(strings.TrimSpace(line.text[colon+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line.number, err)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: duplicate field %q", line.number, key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "name":
+			template.Name = value
+		case "prefix":
+			if template.Prefix, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("line %d: invalid prefix %q", line.number, value)
+			}
+		case "count":
+			if template.Count, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("line %d: invalid count %q", line.number, value)
+			}
+		case "children":
+			if value == "[]" {
+				continue
+			}
+			if value != "" {
+				return nil, fmt.Errorf("line %d: expected a sequence of children", line.number)
+			}
+			if template.Children, err = p.children(indent); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown field %q", line.number, key)
+		}
+	}
+	return template, nil
+}
+
+// children parses a sequence of templates of a mapping at the given indentation. The sequence may
+// be at the indentation of the mapping.
+func (p *yamlParser) children(indent int) ([]*PlanTemplate, error) {
+	if p.pos == len(p.lines) || p.lines[p.pos].dash < indent {
+		return nil, nil
+	}
+
+	var children []*PlanTemplate
+	dash := p.lines[p.pos].dash
+	for p.pos < len(p.lines) && p.lines[p.pos].dash == dash {
+		child, err := p.template(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// parseYAMLPlanTemplate parses a YAML plan template.
+func parseYAMLPlanTemplate(data []byte) (*PlanTemplate, error) {
+	p, err := newYAMLParser(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return nil, fmt.Errorf("empty template")
+	}
+	if p.lines[0].dash >= 0 {
+		return nil, fmt.Errorf("line %d: expected a mapping", p.lines[0].number)
+	}
+
+	template, err := p.template(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return template, nil
+}
@@ -0,0 +1,144 @@
+// go test -v -run="TestParsePlanTemplate"
+
+package cidrman
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePlanTemplate(t *testing.T) {
+	type TestCase struct {
+		Input  string
+		Output *PlanTemplate
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  "name: region\n",
+			Output: &PlanTemplate{Name: "region"},
+			Error:  false,
+		},
+		{
+			Input: `
+# Region with sites and VLANs
+---
+name: region
+children:
+  - name: site   # one per city
+    prefix: 40
+    count: 4
+    children:
+      - name: "vlan #1"
+        prefix: 64
+        count: 16
+      - name: 'link ''a'''
+        prefix: 127
+        children: []
+  - name: loopbacks
+    prefix: 48
+`,
+			Output: &PlanTemplate{Name: "region", Children: []*PlanTemplate{
+				{Name: "site", Prefix: 40, Count: 4, Children: []*PlanTemplate{
+					{Name: "vlan #1", Prefix: 64, Count: 16},
+					{Name: "link 'a'", Prefix: 127},
+				}},
+				{Name: "loopbacks", Prefix: 48},
+			}},
+			Error: false,
+		},
+		{
+			// Sequence at the indentation of its key
+			Input: `name: office
+children:
+- name: users
+  prefix: 26
+  count: 3
+count: 1
+`,
+			Output: &PlanTemplate{Name: "office", Count: 1, Children: []*PlanTemplate{
+				{Name: "users", Prefix: 26, Count: 3},
+			}},
+			Error: false,
+		},
+		{
+			Input:  `{"name": "region", "children": [{"name": "site", "prefix": 40}]}`,
+			Output: &PlanTemplate{Name: "region", Children: []*PlanTemplate{{Name: "site", Prefix: 40}}},
+			Error:  false,
+		},
+		{
+			Input:  "",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: office\nsize: 24\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: office\nname: lab\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: office\nprefix: twenty\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: office\n  prefix: 24\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: office\nchildren:\n  name: users\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: office\nchildren: users\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "- name: office\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: 'office\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name:office\n",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "name: office\nchildren:\n\t- name: users\n",
+			Output: nil,
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := ParsePlanTemplate(strings.NewReader(testCase.Input))
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("ParsePlanTemplate(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("ParsePlanTemplate(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("ParsePlanTemplate(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
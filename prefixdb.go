@@ -0,0 +1,326 @@
+// A read-only prefix database file: a CIDR list, optionally with small values,
+// compiled into sorted intervals that are searched in place, typically mmapped.
+
+package cidrman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+)
+
+// Prefix database file layout, all integers are big-endian:
+//     magic "CIDB" (4 bytes), version (1 byte), value size (1 byte, 0 or 4), reserved (2 bytes)
+//     number of IPv4 intervals (4 bytes), number of IPv6 intervals (4 bytes)
+//     IPv4 intervals: first (4 bytes), last (4 bytes), value (value size bytes)
+//     IPv6 intervals: first (16 bytes), last (16 bytes), value (value size bytes)
+// Intervals are sorted, never overlap and adjacent intervals always have different values.
+const (
+	prefixDBMagic      = "CIDB"
+	prefixDBVersion    = 1
+	prefixDBHeaderSize = 16
+	prefixDBValueSize  = 4
+)
+
+// dbInterval is an interval of addresses with a value, used while compiling a prefix database.
+type dbInterval struct {
+	first *big.Int
+	last  *big.Int
+	value uint32
+}
+
+// dbIntervals is sorted by first address, then larger blocks before the blocks they contain.
+type dbIntervals []*dbInterval
+
+// Sort interface.
+
+func (c dbIntervals) Len() int {
+	return len(c)
+}
+
+func (c dbIntervals) Less(i, j int) bool {
+	if cmp := c[i].first.Cmp(c[j].first); cmp != 0 {
+		return cmp < 0
+	}
+	return c[i].last.Cmp(c[j].last) > 0
+}
+
+func (c dbIntervals) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+// flattenIntervals turns a list of nested or disjoint CIDR blocks into non-overlapping intervals,
+// where the value of the most specific block wins. Identical blocks keep the value of the last one.
+func flattenIntervals(blocks dbIntervals) dbIntervals {
+	sort.Stable(blocks)
+
+	var flat dbIntervals
+	emit := func(first, last *big.Int, value uint32) {
+		if first.Cmp(last) > 0 {
+			return
+		}
+		if n := len(flat); n > 0 && flat[n-1].value == value && big.NewInt(0).Add(flat[n-1].last, big.NewInt(1)).Cmp(first) == 0 {
+			flat[n-1].last = copyUInt128(last)
+			return
+		}
+		flat = append(flat, &dbInterval{first: copyUInt128(first), last: copyUInt128(last), value: value})
+	}
+
+	// Stack of the blocks containing the current address, innermost on top
+	var stack dbIntervals
+	cur := big.NewInt(0)
+	for _, block := range blocks {
+		for len(stack) > 0 && stack[len(stack)-1].last.Cmp(block.first) < 0 {
+			top := stack[len(stack)-1]
+			emit(cur, top.last, top.value)
+			cur.Add(top.last, big.NewInt(1))
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.first.Cmp(block.first) == 0 && top.last.Cmp(block.last) == 0 {
+				// Duplicate block, the last one wins
+				top.value = block.value
+				continue
+			}
+			emit(cur, big.NewInt(0).Sub(block.first, big.NewInt(1)), top.value)
+		}
+		cur.Set(block.first)
+		stack = append(stack, block)
+	}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		emit(cur, top.last, top.value)
+		cur.Add(top.last, big.NewInt(1))
+		stack = stack[:len(stack)-1]
+	}
+
+	return flat
+}
+
+// WritePrefixDB compiles a list of mixed IP networks into a prefix database.
+// The values are optional, with nil values the database only answers Contains.
+// Otherwise there must be one value per network, and where networks overlap
+// the value of the most specific network is used.
+func WritePrefixDB(w io.Writer, nets []*net.IPNet, values []uint32) error {
+	if values != nil && len(values) != len(nets) {
+		return fmt.Errorf("Mismatched number of networks and values: %d, %d", len(nets), len(values))
+	}
+
+	var block4s, block6s dbIntervals
+	for i, net := range nets {
+		var value uint32
+		if values != nil {
+			value = values[i]
+		}
+		ip4, mask, _ := ipv4Network(net)
+		if ip4 != nil {
+			block := newBlock4(ip4, mask)
+			block4s = append(block4s, &dbInterval{
+				first: big.NewInt(0).SetUint64(uint64(block.first)),
+				last:  big.NewInt(0).SetUint64(uint64(block.last)),
+				value: value,
+			})
+		} else {
+			prefix, _ := mask.Size()
+			first := ipv6ToUInt128(net.IP.To16())
+			block6s = append(block6s, &dbInterval{first: first, last: broadcast6(first, uint(prefix)), value: value})
+		}
+	}
+	block4s = flattenIntervals(block4s)
+	block6s = flattenIntervals(block6s)
+
+	valueSize := 0
+	if values != nil {
+		valueSize = prefixDBValueSize
+	}
+	header := make([]byte, prefixDBHeaderSize)
+	copy(header, prefixDBMagic)
+	header[4] = prefixDBVersion
+	header[5] = byte(valueSize)
+	binary.BigEndian.PutUint32(header[8:], uint32(len(block4s)))
+	binary.BigEndian.PutUint32(header[12:], uint32(len(block6s)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]byte, 2*net.IPv6len+valueSize)
+	for _, block := range block4s {
+		binary.BigEndian.PutUint32(record[0:], uint32(block.first.Uint64()))
+		binary.BigEndian.PutUint32(record[4:], uint32(block.last.Uint64()))
+		if valueSize > 0 {
+			binary.BigEndian.PutUint32(record[8:], block.value)
+		}
+		if _, err := w.Write(record[:2*net.IPv4len+valueSize]); err != nil {
+			return err
+		}
+	}
+	for _, block := range block6s {
+		copy(record[0:], uint128ToIPV6(block.first))
+		copy(record[net.IPv6len:], uint128ToIPV6(block.last))
+		if valueSize > 0 {
+			binary.BigEndian.PutUint32(record[2*net.IPv6len:], block.value)
+		}
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PrefixDB is a read-only prefix database. Queries do a binary search directly
+// on the database bytes and do not allocate.
+type PrefixDB struct {
+	data      []byte
+	valueSize int
+	count4    int
+	count6    int
+	size4     int
+	size6     int
+	offset6   int
+	closeFunc func() error
+}
+
+// NewPrefixDB returns a prefix database backed by data, as written by WritePrefixDB.
+// The data must not be modified while the database is in use.
+func NewPrefixDB(data []byte) (*PrefixDB, error) {
+	if len(data) < prefixDBHeaderSize || string(data[:4]) != prefixDBMagic {
+		return nil, errors.New("Invalid prefix database: bad header")
+	}
+	if data[4] != prefixDBVersion {
+		return nil, fmt.Errorf("Unsupported prefix database version: %d", data[4])
+	}
+
+	db := &PrefixDB{
+		data:      data,
+		valueSize: int(data[5]),
+		count4:    int(binary.BigEndian.Uint32(data[8:])),
+		count6:    int(binary.BigEndian.Uint32(data[12:])),
+	}
+	if db.valueSize != 0 && db.valueSize != prefixDBValueSize {
+		return nil, fmt.Errorf("Invalid prefix database: value size %d", db.valueSize)
+	}
+	db.size4 = 2*net.IPv4len + db.valueSize
+	db.size6 = 2*net.IPv6len + db.valueSize
+	db.offset6 = prefixDBHeaderSize + db.count4*db.size4
+	if len(data) != db.offset6+db.count6*db.size6 {
+		return nil, errors.New("Invalid prefix database: size does not match header")
+	}
+
+	return db, nil
+}
+
+// OpenPrefixDB opens a prefix database file, where supported the file is memory-mapped.
+func OpenPrefixDB(path string) (*PrefixDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, closeFunc, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+	db, err := NewPrefixDB(data)
+	if err != nil {
+		closeFunc()
+		return nil, err
+	}
+	db.closeFunc = closeFunc
+	return db, nil
+}
+
+// Close releases the database file, the database is empty afterwards. Close must not be called
+// concurrently with queries, it unmaps the memory they read.
+func (db *PrefixDB) Close() error {
+	db.data = nil
+	db.count4 = 0
+	db.count6 = 0
+	db.offset6 = 0
+	if db.closeFunc == nil {
+		return nil
+	}
+	closeFunc := db.closeFunc
+	db.closeFunc = nil
+	return closeFunc()
+}
+
+// find returns the record containing the address, or nil.
+func (db *PrefixDB) find(ip net.IP) []byte {
+	if db.data == nil {
+		// Closed
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		addr := ipv4ToUInt32(ip4)
+		records := db.data[prefixDBHeaderSize:db.offset6]
+		// Find the first interval ending at or after the address
+		lo, hi := 0, db.count4
+		for lo < hi {
+			mid := int(uint(lo+hi) >> 1)
+			if binary.BigEndian.Uint32(records[mid*db.size4+net.IPv4len:]) < addr {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		if lo == db.count4 {
+			return nil
+		}
+		record := records[lo*db.size4 : (lo+1)*db.size4]
+		if binary.BigEndian.Uint32(record) > addr {
+			return nil
+		}
+		return record
+	}
+
+	ip6 := ip.To16()
+	if ip6 == nil {
+		return nil
+	}
+	records := db.data[db.offset6:]
+	lo, hi := 0, db.count6
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		last := records[mid*db.size6+net.IPv6len : mid*db.size6+2*net.IPv6len]
+		if bytes.Compare(last, ip6) < 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo == db.count6 {
+		return nil
+	}
+	record := records[lo*db.size6 : (lo+1)*db.size6]
+	if bytes.Compare(record[:net.IPv6len], ip6) > 0 {
+		return nil
+	}
+	return record
+}
+
+// Contains reports whether the address is in the database.
+func (db *PrefixDB) Contains(ip net.IP) bool {
+	return db.find(ip) != nil
+}
+
+// Lookup returns the value of the most specific network containing the address,
+// and whether the address is in the database. Without values the value is always 0.
+func (db *PrefixDB) Lookup(ip net.IP) (uint32, bool) {
+	record := db.find(ip)
+	if record == nil {
+		return 0, false
+	}
+	if db.valueSize == 0 {
+		return 0, true
+	}
+	return binary.BigEndian.Uint32(record[len(record)-db.valueSize:]), true
+}
@@ -0,0 +1,185 @@
+// go test -v -run="TestPrefixDB"
+
+package cidrman
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrefixDB(t *testing.T) {
+	type Query struct {
+		IP    string
+		Value uint32
+		Found bool
+	}
+	type TestCase struct {
+		Input   []string
+		Values  []uint32
+		Queries []Query
+		Error   bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:   nil,
+			Values:  nil,
+			Queries: []Query{{"10.0.0.1", 0, false}, {"2001:db8::1", 0, false}},
+			Error:   false,
+		},
+		{
+			Input:  []string{"10.0.0.0/8"},
+			Values: []uint32{1, 2},
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/8",
+				"192.0.2.0/25",
+				"192.0.2.128/25",
+				"2001:db8::/32",
+			},
+			Values: nil,
+			Queries: []Query{
+				{"9.255.255.255", 0, false},
+				{"10.0.0.0", 0, true},
+				{"10.255.255.255", 0, true},
+				{"11.0.0.0", 0, false},
+				{"192.0.2.200", 0, true},
+				{"::ffff:192.0.2.1", 0, true},
+				{"2001:db8:ffff::1", 0, true},
+				{"2001:db9::", 0, false},
+				{"::", 0, false},
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"0.0.0.0/0",
+				"10.0.0.0/8",
+				"10.1.0.0/16",
+				"10.1.2.0/24",
+				"10.2.0.0/16",
+				"10.2.0.0/16",
+				"255.255.255.255/32",
+				"2001:db8::/32",
+				"2001:db8:1::/48",
+			},
+			Values: []uint32{1, 2, 3, 4, 5, 6, 7, 8, 9},
+			Queries: []Query{
+				{"0.0.0.0", 1, true},
+				{"9.255.255.255", 1, true},
+				{"10.0.0.1", 2, true},
+				{"10.1.1.1", 3, true},
+				{"10.1.2.3", 4, true},
+				{"10.1.3.0", 3, true},
+				{"10.2.0.1", 6, true},
+				{"10.3.0.1", 2, true},
+				{"11.0.0.0", 1, true},
+				{"255.255.255.254", 1, true},
+				{"255.255.255.255", 7, true},
+				{"2001:db8::1", 8, true},
+				{"2001:db8:1::1", 9, true},
+				{"2001:db8:2::1", 8, true},
+				{"2001:db9::1", 0, false},
+			},
+			Error: false,
+		},
+		{
+			// IPv4-mapped networks are IPv4 networks from prefix length 96
+			Input: []string{
+				"::ffff:10.0.0.0/104",
+				"::ffff:0.0.0.0/80",
+			},
+			Values: []uint32{1, 2},
+			Queries: []Query{
+				{"10.1.2.3", 1, true},
+				{"192.0.2.1", 0, false},
+				{"::1", 2, true},
+			},
+			Error: false,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "cidrman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, testCase := range testCases {
+		var nets []*net.IPNet
+		for _, cidr := range testCase.Input {
+			_, network, _ := net.ParseCIDR(cidr)
+			nets = append(nets, network)
+		}
+
+		var buf bytes.Buffer
+		err := WritePrefixDB(&buf, nets, testCase.Values)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("WritePrefixDB(%#v, %#v) failed: %s", testCase.Input, testCase.Values, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("WritePrefixDB(%#v, %#v) expected error", testCase.Input, testCase.Values)
+			continue
+		}
+
+		path := filepath.Join(dir, "test.db")
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		db, err := OpenPrefixDB(path)
+		if err != nil {
+			t.Errorf("OpenPrefixDB(%#v) failed: %s", testCase.Input, err.Error())
+			continue
+		}
+		for _, query := range testCase.Queries {
+			ip := net.ParseIP(query.IP)
+			value, found := db.Lookup(ip)
+			if value != query.Value || found != query.Found {
+				t.Errorf("Lookup(%#v, %s) expected: %d %t, got: %d %t", testCase.Input, query.IP, query.Value, query.Found, value, found)
+			}
+			if db.Contains(ip) != query.Found {
+				t.Errorf("Contains(%#v, %s) expected: %t", testCase.Input, query.IP, query.Found)
+			}
+			allocs := testing.AllocsPerRun(10, func() {
+				db.Lookup(ip)
+			})
+			if allocs != 0 {
+				t.Errorf("Lookup(%#v, %s) allocated %v times", testCase.Input, query.IP, allocs)
+			}
+		}
+		if err := db.Close(); err != nil {
+			t.Errorf("Close(%#v) failed: %s", testCase.Input, err.Error())
+		}
+		for _, query := range testCase.Queries {
+			if value, found := db.Lookup(net.ParseIP(query.IP)); value != 0 || found {
+				t.Errorf("Lookup(%#v, %s) after Close expected: 0 false, got: %d %t", testCase.Input, query.IP, value, found)
+			}
+		}
+	}
+}
+
+func TestPrefixDBInvalid(t *testing.T) {
+	testCases := [][]byte{
+		nil,
+		[]byte("CIDB"),
+		[]byte("XXXX\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"),
+		[]byte("CIDB\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"),
+		[]byte("CIDB\x01\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"),
+		[]byte("CIDB\x01\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00"),
+	}
+
+	for _, testCase := range testCases {
+		if _, err := NewPrefixDB(testCase); err == nil {
+			t.Errorf("NewPrefixDB(%#v) expected error", testCase)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package cidrman
+
+import (
+	"net"
+	"sort"
+)
+
+// Provenance is an output CIDR block of MergeWithProvenance or RemoveWithProvenance
+// with the input entries it came from.
+type Provenance struct {
+	Net *net.IPNet
+	// Inputs are the indexes of the input entries overlapping the output CIDR block, ascending.
+	Inputs []int
+}
+
+// RemoveCut is an input entry of RemoveWithProvenance with the remove entries that cut into it.
+type RemoveCut struct {
+	Input int
+	// Removes are the indexes of the remove entries overlapping the input entry, ascending.
+	Removes []int
+}
+
+// provenanceBlock is the address family, first and last address and prefix length of a CIDR block.
+type provenanceBlock struct {
+	sortKey
+	last uint128
+}
+
+// newProvenanceBlock returns the block of a network, from its sort key with IPv4-mapped
+// networks like MergeIPNets.
+func newProvenanceBlock(n *net.IPNet) provenanceBlock {
+	key, _ := newSortKey(n)
+	return provenanceBlock{key, key.last()}
+}
+
+// before reports whether b is entirely before the first address of o.
+func (b provenanceBlock) before(o provenanceBlock) bool {
+	if b.width != o.width {
+		return b.width < o.width
+	}
+	return b.last.less(o.addr)
+}
+
+// overlaps reports whether b and o have addresses in common.
+func (b provenanceBlock) overlaps(o provenanceBlock) bool {
+	return b.width == o.width && !b.last.less(o.addr) && !o.last.less(b.addr)
+}
+
+// parseProvenanceBlocks parses a list of CIDR blocks.
+func parseProvenanceBlocks(cidrs []string) ([]*net.IPNet, []provenanceBlock, error) {
+	var networks []*net.IPNet
+	var blocks []provenanceBlock
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, nil, err
+		}
+		networks = append(networks, network)
+		blocks = append(blocks, newProvenanceBlock(network))
+	}
+	return networks, blocks, nil
+}
+
+// provenance returns the outputs with the indexes of the overlapping inputs.
+// The outputs are disjoint and in ascending order, as returned by MergeIPNets and RemoveIPNets.
+func provenance(outputs []*net.IPNet, inputs []provenanceBlock) []Provenance {
+	result := make([]Provenance, len(outputs))
+	blocks := make([]provenanceBlock, len(outputs))
+	for i, output := range outputs {
+		result[i].Net = output
+		blocks[i] = newProvenanceBlock(output)
+	}
+
+	// Each input overlaps a run of consecutive outputs
+	for i, input := range inputs {
+		j := sort.Search(len(blocks), func(j int) bool {
+			return !blocks[j].before(input)
+		})
+		for ; j < len(blocks) && blocks[j].overlaps(input); j++ {
+			result[j].Inputs = append(result[j].Inputs, i)
+		}
+	}
+	return result
+}
+
+// MergeWithProvenance merges a list of mixed CIDR blocks like MergeCIDRs and returns, for each
+// output CIDR block, the indexes of the input entries that were merged into it.
+// Example:
+//     merged, err := MergeWithProvenance([]string{"192.0.2.0/25", "10.0.0.0/8", "192.0.2.128/25"})
+//     // 10.0.0.0/8 from [1], 192.0.2.0/24 from [0 2]
+func MergeWithProvenance(cidrs []string) ([]Provenance, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+	if len(cidrs) == 0 {
+		return make([]Provenance, 0), nil
+	}
+
+	networks, blocks, err := parseProvenanceBlocks(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := MergeIPNets(networks)
+	if err != nil {
+		return nil, err
+	}
+
+	return provenance(merged, blocks), nil
+}
+
+// RemoveWithProvenance removes the second list of mixed CIDR blocks from the first like RemoveCIDRs
+// and returns, for each output CIDR block, the indexes of the input entries it came from, and for
+// each input entry that was cut, the indexes of the remove entries that cut into it.
+func RemoveWithProvenance(cidrs, removes []string) ([]Provenance, []RemoveCut, error) {
+	if cidrs == nil {
+		return nil, nil, nil
+	}
+	if len(cidrs) == 0 {
+		return make([]Provenance, 0), nil, nil
+	}
+
+	networks, blocks, err := parseProvenanceBlocks(cidrs)
+	if err != nil {
+		return nil, nil, err
+	}
+	rmnetworks, rmblocks, err := parseProvenanceBlocks(removes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(removes) == 0 {
+		// Like RemoveCIDRs, the input is returned unchanged
+		result := make([]Provenance, len(networks))
+		for i, network := range networks {
+			result[i] = Provenance{Net: network, Inputs: []int{i}}
+		}
+		return result, nil, nil
+	}
+	remaining, err := RemoveIPNets(networks, rmnetworks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// CIDR blocks that overlap are nested. The removes overlapping an input are the removes
+	// containing it, found by their network at each shorter prefix, and the removes inside it,
+	// a run of the removes sorted by address.
+	containing := make(map[sortKey][]int)
+	for j, block := range rmblocks {
+		containing[block.sortKey] = append(containing[block.sortKey], j)
+	}
+	order, _ := sortedIndexes(rmnetworks, SortByAddress)
+
+	var cuts []RemoveCut
+	for i, block := range blocks {
+		var cut []int
+		for prefix := 0; prefix <= block.prefix; prefix++ {
+			hostmask := hostmask128(block.width - uint(prefix))
+			network := uint128{block.addr.hi &^ hostmask.hi, block.addr.lo &^ hostmask.lo}
+			cut = append(cut, containing[sortKey{block.width, network, prefix}]...)
+		}
+		k := sort.Search(len(order), func(k int) bool {
+			rmblock := rmblocks[order[k]]
+			return rmblock.width > block.width || (rmblock.width == block.width && !rmblock.addr.less(block.addr))
+		})
+		for ; k < len(order) && rmblocks[order[k]].overlaps(block); k++ {
+			if rmblocks[order[k]].prefix > block.prefix {
+				cut = append(cut, order[k])
+			}
+		}
+		if len(cut) > 0 {
+			sort.Ints(cut)
+			cuts = append(cuts, RemoveCut{Input: i, Removes: cut})
+		}
+	}
+
+	return provenance(remaining, blocks), cuts, nil
+}
@@ -0,0 +1,194 @@
+// go test -v -run="TestMergeWithProvenance|TestRemoveWithProvenance"
+
+package cidrman
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// provenanceStrings returns the output CIDR blocks with their inputs, like "192.0.2.0/24 [0 2]".
+func provenanceStrings(provenances []Provenance) []string {
+	if provenances == nil {
+		return nil
+	}
+	result := make([]string, len(provenances))
+	for i, p := range provenances {
+		result[i] = fmt.Sprintf("%s %v", p.Net, p.Inputs)
+	}
+	return result
+}
+
+func TestMergeWithProvenance(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input:  []string{},
+			Output: []string{},
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/25",
+				"10.0.0.0/8",
+				"192.0.2.128/25",
+			},
+			Output: []string{
+				"10.0.0.0/8 [1]",
+				"192.0.2.0/24 [0 2]",
+			},
+			Error: false,
+		},
+		{
+			// IPv4-mapped networks are IPv4 networks from prefix length 96
+			Input: []string{
+				"::ffff:10.0.0.0/104",
+				"192.0.2.0/24",
+				"10.1.0.0/16",
+			},
+			Output: []string{
+				"10.0.0.0/8 [0 2]",
+				"192.0.2.0/24 [1]",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/16",
+				"2001:db8::/33",
+				"10.0.0.0/8",
+				"2001:db8:8000::/33",
+				"10.0.0.5/32",
+				"11.0.0.0/8",
+			},
+			Output: []string{
+				"10.0.0.0/7 [0 2 4 5]",
+				"2001:db8::/32 [1 3]",
+			},
+			Error: false,
+		},
+		{
+			Input:  []string{"10.0.0.0/8", "10.0.0.0/33"},
+			Output: nil,
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := MergeWithProvenance(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("MergeWithProvenance(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("MergeWithProvenance(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, provenanceStrings(output)) {
+			t.Errorf("MergeWithProvenance(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, provenanceStrings(output))
+		}
+	}
+}
+
+func TestRemoveWithProvenance(t *testing.T) {
+	type TestCase struct {
+		Input   []string
+		Removes []string
+		Output  []string
+		Cuts    []RemoveCut
+		Error   bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:   nil,
+			Removes: []string{"10.0.0.0/8"},
+			Output:  nil,
+			Cuts:    nil,
+			Error:   false,
+		},
+		{
+			Input:   []string{},
+			Removes: []string{"10.0.0.0/8"},
+			Output:  []string{},
+			Cuts:    nil,
+			Error:   false,
+		},
+		{
+			Input:   []string{"10.0.1.0/24", "10.0.0.0/24"},
+			Removes: []string{},
+			Output: []string{
+				"10.0.1.0/24 [0]",
+				"10.0.0.0/24 [1]",
+			},
+			Cuts:  nil,
+			Error: false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/24",
+				"192.0.2.0/24",
+				"10.0.0.0/25",
+				"2001:db8::/32",
+			},
+			Removes: []string{
+				"10.0.0.0/26",
+				"192.0.0.0/16",
+				"2001:db8::/32",
+				"172.16.0.0/12",
+				"10.0.0.32/27",
+			},
+			Output: []string{
+				"10.0.0.64/26 [0 2]",
+				"10.0.0.128/25 [0]",
+			},
+			Cuts: []RemoveCut{
+				{Input: 0, Removes: []int{0, 4}},
+				{Input: 1, Removes: []int{1}},
+				{Input: 2, Removes: []int{0, 4}},
+				{Input: 3, Removes: []int{2}},
+			},
+			Error: false,
+		},
+		{
+			Input:   []string{"10.0.0.0/8"},
+			Removes: []string{"10.0.0.0"},
+			Output:  nil,
+			Cuts:    nil,
+			Error:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, cuts, err := RemoveWithProvenance(testCase.Input, testCase.Removes)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("RemoveWithProvenance(%#v, %#v) failed: %s", testCase.Input, testCase.Removes, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("RemoveWithProvenance(%#v, %#v) expected error, got: %#v", testCase.Input, testCase.Removes, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, provenanceStrings(output)) {
+			t.Errorf("RemoveWithProvenance(%#v, %#v) expected: %#v, got: %#v", testCase.Input, testCase.Removes, testCase.Output, provenanceStrings(output))
+		}
+		if !reflect.DeepEqual(testCase.Cuts, cuts) {
+			t.Errorf("RemoveWithProvenance(%#v, %#v) expected cuts: %#v, got: %#v", testCase.Input, testCase.Removes, testCase.Cuts, cuts)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cidrman
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net"
+)
+
+// blockOffset returns the address at offset from the start of an IP network.
+func blockOffset(n *net.IPNet, offset *big.Int) net.IP {
+	if ip4 := n.IP.To4(); ip4 != nil {
+		return uint32ToIPV4(ipv4ToUInt32(ip4) + uint32(offset.Uint64()))
+	}
+	return uint128ToIPV6(big.NewInt(0).Add(ipv6ToUInt128(n.IP.To16()), offset))
+}
+
+// RandomAddr returns a uniformly random address from a list of mixed IP networks.
+// Every address in the merged list is equally likely, so larger blocks are picked more often.
+// Mixing IPv4 and IPv6 networks will almost always return an IPv6 address.
+// The caller supplied rand.Source makes the result reproducible.
+func RandomAddr(nets []*net.IPNet, src rand.Source) (net.IP, error) {
+	if len(nets) == 0 {
+		return nil, errors.New("No networks to pick an address from")
+	}
+
+	nets, err := MergeIPNets(nets)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make([]*big.Int, len(nets))
+	total := big.NewInt(0)
+	for i, network := range nets {
+		ones, bits := network.Mask.Size()
+		sizes[i] = big.NewInt(0).Lsh(big.NewInt(1), uint(bits-ones))
+		total.Add(total, sizes[i])
+	}
+
+	offset := big.NewInt(0).Rand(rand.New(src), total)
+	for i, network := range nets {
+		if offset.Cmp(sizes[i]) < 0 {
+			return blockOffset(network, offset), nil
+		}
+		offset.Sub(offset, sizes[i])
+	}
+
+	return nil, errors.New("Random offset out of range")
+}
+
+// RandomPrefix returns a uniformly random prefix of the given length from a list of mixed IP networks.
+// Only prefixes that are fully inside the list are considered, and each of them is equally likely.
+// The length applies to both IPv4 and IPv6 networks, so the list should normally hold one family.
+func RandomPrefix(nets []*net.IPNet, length int, src rand.Source) (*net.IPNet, error) {
+	if len(nets) == 0 {
+		return nil, errors.New("No networks to pick a prefix from")
+	}
+
+	nets, err := MergeIPNets(nets)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every block in the minimal list holds 2^(length-prefix) aligned prefixes of the given length
+	counts := make([]*big.Int, len(nets))
+	total := big.NewInt(0)
+	for i, network := range nets {
+		ones, bits := network.Mask.Size()
+		counts[i] = big.NewInt(0)
+		if ones <= length && length <= bits {
+			counts[i].Lsh(big.NewInt(1), uint(length-ones))
+		}
+		total.Add(total, counts[i])
+	}
+	if total.Sign() == 0 {
+		return nil, fmt.Errorf("No /%d prefixes in networks", length)
+	}
+
+	index := big.NewInt(0).Rand(rand.New(src), total)
+	for i, network := range nets {
+		if index.Cmp(counts[i]) < 0 {
+			_, bits := network.Mask.Size()
+			offset := index.Lsh(index, uint(bits-length))
+			return &net.IPNet{IP: blockOffset(network, offset), Mask: net.CIDRMask(length, bits)}, nil
+		}
+		index.Sub(index, counts[i])
+	}
+
+	return nil, errors.New("Random prefix out of range")
+}
+
+// RandomPrefixes returns count random non-overlapping prefixes of the given length from a list of mixed IP networks.
+// Each prefix is picked with RandomPrefix and then removed from the list before picking the next.
+func RandomPrefixes(nets []*net.IPNet, length, count int, src rand.Source) ([]*net.IPNet, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("Invalid prefix count: %d", count)
+	}
+
+	prefixes := make([]*net.IPNet, 0, count)
+	for len(prefixes) < count {
+		prefix, err := RandomPrefix(nets, length, src)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+
+		nets, err = RemoveIPNets(nets, []*net.IPNet{prefix})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,154 @@
+// go test -v -run="TestRandom"
+
+package cidrman
+
+import (
+	"math/rand"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestRandom(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Length int
+		Count  int
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Length: 24,
+			Count:  1,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/25",
+			},
+			Length: 24,
+			Count:  1,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/24",
+			},
+			Length: 26,
+			Count:  5,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/24",
+			},
+			Length: 26,
+			Count:  -1,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"192.0.2.7/32",
+			},
+			Length: 32,
+			Count:  1,
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/24",
+			},
+			Length: 26,
+			Count:  4,
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/8",
+				"192.0.2.0/25",
+				"198.51.100.0/24",
+			},
+			Length: 24,
+			Count:  20,
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"2001:db8::/32",
+				"2001:db8:ffff::/48",
+			},
+			Length: 56,
+			Count:  20,
+			Error:  false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		var nets []*net.IPNet
+		for _, cidr := range testCase.Input {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				t.Fatalf("net.ParseCIDR(%#v) failed: %s", cidr, err.Error())
+			}
+			nets = append(nets, network)
+		}
+		contains := func(ip net.IP) bool {
+			for _, network := range nets {
+				if network.Contains(ip) {
+					return true
+				}
+			}
+			return false
+		}
+
+		prefixes, err := RandomPrefixes(nets, testCase.Length, testCase.Count, rand.NewSource(1))
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("RandomPrefixes(%#v, %d, %d) failed: %s", testCase.Input, testCase.Length, testCase.Count, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("RandomPrefixes(%#v, %d, %d) expected error, got: %v", testCase.Input, testCase.Length, testCase.Count, prefixes)
+			continue
+		}
+		if len(prefixes) != testCase.Count {
+			t.Errorf("RandomPrefixes(%#v, %d, %d) expected %d prefixes, got: %v", testCase.Input, testCase.Length, testCase.Count, testCase.Count, prefixes)
+		}
+		for _, prefix := range prefixes {
+			ones, bits := prefix.Mask.Size()
+			last := blockOffset(prefix, hostmask6(uint(widthUInt128-bits+ones)))
+			if ones != testCase.Length || !contains(prefix.IP) || !contains(last) {
+				t.Errorf("RandomPrefixes(%#v, %d, %d) returned prefix outside the networks: %v", testCase.Input, testCase.Length, testCase.Count, prefix)
+			}
+		}
+		// Prefixes of the same length overlap only if they are equal
+		for i := range prefixes {
+			for j := i + 1; j < len(prefixes); j++ {
+				if prefixes[i].String() == prefixes[j].String() {
+					t.Errorf("RandomPrefixes(%#v, %d, %d) returned overlapping prefix: %v", testCase.Input, testCase.Length, testCase.Count, prefixes[i])
+				}
+			}
+		}
+
+		// The same source seed gives the same result
+		again, _ := RandomPrefixes(nets, testCase.Length, testCase.Count, rand.NewSource(1))
+		if !reflect.DeepEqual(prefixes, again) {
+			t.Errorf("RandomPrefixes(%#v, %d, %d) not reproducible: %v, %v", testCase.Input, testCase.Length, testCase.Count, prefixes, again)
+		}
+
+		src := rand.NewSource(1)
+		for i := 0; i < 100; i++ {
+			ip, err := RandomAddr(nets, src)
+			if err != nil {
+				t.Errorf("RandomAddr(%#v) failed: %s", testCase.Input, err.Error())
+				break
+			}
+			if !contains(ip) {
+				t.Errorf("RandomAddr(%#v) returned address outside the networks: %v", testCase.Input, ip)
+			}
+		}
+	}
+}
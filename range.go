@@ -58,19 +58,5 @@ func IPRangeToIPNets(start, end net.IP) ([]*net.IPNet, error) {
 // IPRangeToCIDRs accepts an arbitrary start and end IP address and returns a list of
 // CIDR subnets that fit exactly between the boundaries of the two with no overlap.
 func IPRangeToCIDRs(start, end string) ([]string, error) {
-	ipStart := net.ParseIP(start)
-	if ipStart == nil {
-		return nil, fmt.Errorf("Invalid IP address: %s", start)
-	}
-	ipEnd := net.ParseIP(end)
-	if ipEnd == nil {
-		return nil, fmt.Errorf("Invalid IP address: %s", end)
-	}
-
-	nets, err := IPRangeToIPNets(ipStart, ipEnd)
-	if err != nil {
-		return nil, err
-	}
-
-	return ipNets(nets).toCIDRs(), nil
+	return IPRangeToCIDRsWithOptions(start, end, Options{})
 }
@@ -9,52 +9,56 @@ import (
 // Example:
 //     routableNets, err := RemoveIPNets(mixedListOfNets, rfc1918nets)
 func RemoveIPNets(nets, rmnets []*net.IPNet) ([]*net.IPNet, error) {
+	return Remove(nets, rmnets)
+}
+
+// Remove is RemoveIPNets with options.
+// Example:
+//     remaining, err := Remove(nets, rmnets, WithOrder(SortByPrefixLength))
+func Remove(nets, rmnets []*net.IPNet, opts ...Option) ([]*net.IPNet, error) {
+	o := newOptions(opts)
+	result, err := remove(nets, rmnets, o)
+	if err != nil {
+		return nil, err
+	}
+	return o.sorted(result)
+}
+
+// remove removes the second list of IP networks from the first with options, IPv4 before IPv6, each by address.
+func remove(nets, rmnets []*net.IPNet, o Options) ([]*net.IPNet, error) {
 	if nets == nil {
 		return nil, nil
 	}
 	if len(nets) == 0 {
 		return make([]*net.IPNet, 0), nil
 	}
-	if rmnets == nil {
-		return nets, nil
-	}
 	if len(rmnets) == 0 {
-		return nets, nil
+		if o.unchanged() {
+			return nets, nil
+		}
+		// Nothing to remove, but the networks still go through the options
+		return merge(nets, o)
 	}
 
 	// Merge nets and rmnet individually to have the miminal set of largets networks
-	nets, err := MergeIPNets(nets)
+	nets, err := merge(nets, o)
 	if err != nil {
 		return nil, err
 	}
-	rmnets, err = MergeIPNets(rmnets)
+	rmnets, err = merge(rmnets, o)
 	if err != nil {
 		return nil, err
 	}
 
 	// Split into IPv4 and IPv6 lists.
 	// Handle the lists separately and then combine.
-	var block4s cidrBlock4s
-	var block6s cidrBlock6s
-	for _, net := range nets {
-		ip4 := net.IP.To4()
-		if ip4 != nil {
-			block4s = append(block4s, newBlock4(ip4, net.Mask))
-		} else {
-			ip6 := net.IP.To16()
-			block6s = append(block6s, newBlock6(ip6, net.Mask))
-		}
+	block4s, block6s, err := o.splitFamilies(nets)
+	if err != nil {
+		return nil, err
 	}
-	var remove4s cidrBlock4s
-	var remove6s cidrBlock6s
-	for _, net := range rmnets {
-		ip4 := net.IP.To4()
-		if ip4 != nil {
-			remove4s = append(remove4s, newBlock4(ip4, net.Mask))
-		} else {
-			ip6 := net.IP.To16()
-			remove6s = append(remove6s, newBlock6(ip6, net.Mask))
-		}
+	remove4s, remove6s, err := o.splitFamilies(rmnets)
+	if err != nil {
+		return nil, err
 	}
 
 	var new4s []*net.IPNet
@@ -79,44 +83,5 @@ func RemoveIPNets(nets, rmnets []*net.IPNet) ([]*net.IPNet, error) {
 
 // RemoveCIDRs accepts two lists of mixed CIDR blocks and removes the second list from the first and return new a list of CIDRs.
 func RemoveCIDRs(cidrs, removes []string) ([]string, error) {
-	if cidrs == nil {
-		return nil, nil
-	}
-	if len(cidrs) == 0 {
-		return make([]string, 0), nil
-	}
-	if removes == nil {
-		return cidrs, nil
-	}
-	if len(removes) == 0 {
-		return cidrs, nil
-	}
-
-	var networks []*net.IPNet
-	for _, cidr := range cidrs {
-		_, network, err := net.ParseCIDR(cidr)
-		if err != nil {
-			return nil, err
-		}
-		networks = append(networks, network)
-	}
-	var rmnets []*net.IPNet
-	for _, cidr := range removes {
-		_, network, err := net.ParseCIDR(cidr)
-		if err != nil {
-			return nil, err
-		}
-		rmnets = append(rmnets, network)
-	}
-
-	newNets, err := RemoveIPNets(networks, rmnets)
-	if err != nil {
-		return nil, err
-	}
-	// Handle the situation where all cidrs were removed
-	if len(newNets) == 0 {
-		return make([]string, 0), nil
-	}
-
-	return ipNets(newNets).toCIDRs(), nil
+	return RemoveCIDRsWithOptions(cidrs, removes, Options{})
 }
@@ -0,0 +1,191 @@
+package cidrman
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+)
+
+// Reverse DNS zones are octet aligned for IPv4 and nibble aligned for IPv6.
+const (
+	zoneStep4 = 8
+	zoneStep6 = 4
+)
+
+// classlessPrefix4 is the longest octet aligned IPv4 zone, blocks smaller than this
+// are delegated with RFC 2317 classless zones.
+const classlessPrefix4 = 24
+
+// zoneName4 returns the in-addr.arpa zone name for an octet aligned IPv4 network.
+func zoneName4(addr uint32, prefix uint) string {
+	ip := uint32ToIPV4(addr)
+	labels := make([]string, 0, prefix/zoneStep4+1)
+	for i := int(prefix/zoneStep4) - 1; i >= 0; i-- {
+		labels = append(labels, fmt.Sprintf("%d", ip[i]))
+	}
+	labels = append(labels, "in-addr.arpa")
+	return strings.Join(labels, ".")
+}
+
+// classlessZoneName4 returns the RFC 2317 zone name for an IPv4 network smaller than a /24,
+// e.g. 0/26.2.0.192.in-addr.arpa for 192.0.2.0/26.
+func classlessZoneName4(addr uint32, prefix uint) string {
+	return fmt.Sprintf("%d/%d.%s", addr&0xff, prefix, zoneName4(network4(addr, classlessPrefix4), classlessPrefix4))
+}
+
+// zoneName6 returns the ip6.arpa zone name for a nibble aligned IPv6 network.
+func zoneName6(addr *big.Int, prefix uint) string {
+	ip := uint128ToIPV6(addr)
+	labels := make([]string, 0, prefix/zoneStep6+1)
+	for i := int(prefix/zoneStep6) - 1; i >= 0; i-- {
+		nibble := ip[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		labels = append(labels, fmt.Sprintf("%x", nibble&0xf))
+	}
+	labels = append(labels, "ip6.arpa")
+	return strings.Join(labels, ".")
+}
+
+// splitZones4 recursively computes the reverse zones to cover the range lo to hi.
+// The network addr/prefix must be octet aligned.
+func splitZones4(addr uint32, prefix uint, lo, hi uint32, zones *[]string) error {
+	bc := broadcast4(addr, prefix)
+	if (lo < addr) || (hi > bc) {
+		return fmt.Errorf("%d, %d out of range for network %d/%d, broadcast %d", lo, hi, addr, prefix, bc)
+	}
+
+	if (lo == addr) && (hi == bc) {
+		*zones = append(*zones, zoneName4(addr, prefix))
+		return nil
+	}
+
+	if prefix == classlessPrefix4 {
+		// Partial /24, use RFC 2317 classless delegation.
+		var cidrs []*net.IPNet
+		if err := splitRange4(addr, prefix, lo, hi, &cidrs); err != nil {
+			return err
+		}
+		for _, cidr := range cidrs {
+			ones, _ := cidr.Mask.Size()
+			*zones = append(*zones, classlessZoneName4(ipv4ToUInt32(cidr.IP), uint(ones)))
+		}
+		return nil
+	}
+
+	prefix += zoneStep4
+	for child := network4(lo, prefix); ; child += 1 << (widthUInt32 - prefix) {
+		childLo, childHi := child, broadcast4(child, prefix)
+		if lo > childLo {
+			childLo = lo
+		}
+		last := hi <= childHi
+		if last {
+			childHi = hi
+		}
+		if err := splitZones4(child, prefix, childLo, childHi, zones); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// splitZones6 recursively computes the reverse zones to cover the range lo to hi.
+// The network addr/prefix must be nibble aligned.
+func splitZones6(addr *big.Int, prefix uint, lo, hi *big.Int, zones *[]string) error {
+	bc := broadcast6(addr, prefix)
+	if (lo.Cmp(addr) < 0) || (hi.Cmp(bc) > 0) {
+		return fmt.Errorf("%v, %v out of range for network %v/%d, broadcast %v", uint128ToIPV6(lo), uint128ToIPV6(hi), uint128ToIPV6(addr), prefix, uint128ToIPV6(bc))
+	}
+
+	if (lo.Cmp(addr) == 0) && (hi.Cmp(bc) == 0) {
+		*zones = append(*zones, zoneName6(addr, prefix))
+		return nil
+	}
+
+	prefix += zoneStep6
+	size := big.NewInt(0).Lsh(big.NewInt(1), widthUInt128-prefix)
+	for child := network6(lo, prefix); ; child = big.NewInt(0).Add(child, size) {
+		childLo, childHi := child, broadcast6(child, prefix)
+		if lo.Cmp(childLo) > 0 {
+			childLo = lo
+		}
+		last := hi.Cmp(childHi) <= 0
+		if last {
+			childHi = hi
+		}
+		if err := splitZones6(child, prefix, childLo, childHi, zones); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// ReverseZonesIPNets accepts a list of IP networks and returns the reverse DNS zones that cover them.
+// IPv4 zones are octet aligned (in-addr.arpa) with RFC 2317 classless zones for blocks smaller than a /24,
+// IPv6 zones are nibble aligned (ip6.arpa).
+func ReverseZonesIPNets(nets []*net.IPNet) ([]string, error) {
+	if nets == nil {
+		return nil, nil
+	}
+	if len(nets) == 0 {
+		return make([]string, 0), nil
+	}
+
+	// Merge nets to get the minimal set of largest networks to split
+	nets, err := MergeIPNets(nets)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []string
+	for _, net := range nets {
+		prefix, _ := net.Mask.Size()
+		ip4 := net.IP.To4()
+		if ip4 != nil {
+			lo := ipv4ToUInt32(ip4)
+			hi := broadcast4(lo, uint(prefix))
+			if err := splitZones4(0, 0, lo, hi, &zones); err != nil {
+				return nil, err
+			}
+		} else {
+			lo := ipv6ToUInt128(net.IP.To16())
+			hi := broadcast6(lo, uint(prefix))
+			if err := splitZones6(big.NewInt(0), 0, lo, hi, &zones); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return zones, nil
+}
+
+// ReverseZones accepts a list of CIDR blocks and returns the reverse DNS zones that cover them.
+// Example:
+//     zones, err := ReverseZones([]string{"192.0.2.0/23", "198.51.100.64/26"})
+//     // [2.0.192.in-addr.arpa 3.0.192.in-addr.arpa 64/26.100.51.198.in-addr.arpa]
+func ReverseZones(cidrs []string) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+	if len(cidrs) == 0 {
+		return make([]string, 0), nil
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return ReverseZonesIPNets(networks)
+}
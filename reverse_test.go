@@ -0,0 +1,194 @@
+// go test -v -run="TestReverseZones"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReverseZones(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input:  []string{},
+			Output: []string{},
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/33",
+			},
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input: []string{
+				"0.0.0.0/0",
+			},
+			Output: []string{
+				"in-addr.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/8",
+			},
+			Output: []string{
+				"10.in-addr.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/23",
+			},
+			Output: []string{
+				"2.0.192.in-addr.arpa",
+				"3.0.192.in-addr.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"172.16.0.0/15",
+			},
+			Output: []string{
+				"16.172.in-addr.arpa",
+				"17.172.in-addr.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"198.51.100.64/26",
+			},
+			Output: []string{
+				"64/26.100.51.198.in-addr.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"198.51.100.0/25",
+				"198.51.100.128/26",
+				"198.51.100.192/32",
+			},
+			Output: []string{
+				"0/25.100.51.198.in-addr.arpa",
+				"128/26.100.51.198.in-addr.arpa",
+				"192/32.100.51.198.in-addr.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"198.51.100.0/25",
+				"198.51.100.128/25",
+			},
+			Output: []string{
+				"100.51.198.in-addr.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"255.255.255.0/24",
+			},
+			Output: []string{
+				"255.255.255.in-addr.arpa",
+			},
+			Error: false,
+		},
+		// IPv6 tests
+		{
+			Input: []string{
+				"::/0",
+			},
+			Output: []string{
+				"ip6.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"2001:db8::/32",
+			},
+			Output: []string{
+				"8.b.d.0.1.0.0.2.ip6.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"2001:db8::/31",
+			},
+			Output: []string{
+				"8.b.d.0.1.0.0.2.ip6.arpa",
+				"9.b.d.0.1.0.0.2.ip6.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"2001:db8:0:10::/62",
+			},
+			Output: []string{
+				"0.1.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				"1.1.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				"2.1.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				"3.1.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+			},
+			Error: false,
+		},
+		{
+			Input: []string{
+				"2001:db8::1/128",
+			},
+			Output: []string{
+				"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+			},
+			Error: false,
+		},
+		// Mixed IPv4 and IPv6 tests
+		{
+			Input: []string{
+				"2001:db8::/32",
+				"192.0.2.0/24",
+			},
+			Output: []string{
+				"2.0.192.in-addr.arpa",
+				"8.b.d.0.1.0.0.2.ip6.arpa",
+			},
+			Error: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := ReverseZones(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("ReverseZones(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("ReverseZones(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("ReverseZones(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
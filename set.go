@@ -0,0 +1,78 @@
+package cidrman
+
+import (
+	"math/big"
+	"net"
+)
+
+// Set is a list of mixed IP networks kept in its merged form,
+// the smallest possible list of IPNets with IPv4 before IPv6.
+type Set struct {
+	nets []*net.IPNet
+}
+
+// NewSet returns a new set of the merged IP networks.
+func NewSet(nets []*net.IPNet) (*Set, error) {
+	merged, err := MergeIPNets(nets)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Set{nets: merged}, nil
+}
+
+// NewSetCIDRs returns a new set of the merged CIDR blocks.
+func NewSetCIDRs(cidrs []string) (*Set, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return NewSet(networks)
+}
+
+// IPNets returns the merged IP networks of the set.
+func (s *Set) IPNets() []*net.IPNet {
+	return s.nets
+}
+
+// CIDRs returns the merged CIDR blocks of the set.
+func (s *Set) CIDRs() []string {
+	return ipNets(s.nets).toCIDRs()
+}
+
+// Len returns the number of CIDR blocks in the set.
+func (s *Set) Len() int {
+	return len(s.nets)
+}
+
+// blocks returns the set as coalesced IPv4 and IPv6 intervals, each in ascending order.
+func (s *Set) blocks() (cidrBlock4s, cidrBlock6s) {
+	var block4s cidrBlock4s
+	var block6s cidrBlock6s
+	for _, net := range s.nets {
+		ip4 := net.IP.To4()
+		if ip4 != nil {
+			block := newBlock4(ip4, net.Mask)
+			last := len(block4s) - 1
+			if last >= 0 && block4s[last].last+1 == block.first {
+				block4s[last].last = block.last
+			} else {
+				block4s = append(block4s, block)
+			}
+		} else {
+			block := newBlock6(net.IP.To16(), net.Mask)
+			last := len(block6s) - 1
+			if last >= 0 && big.NewInt(0).Add(block6s[last].last, big.NewInt(1)).Cmp(block.first) == 0 {
+				block6s[last].last = block.last
+			} else {
+				block6s = append(block6s, block)
+			}
+		}
+	}
+	return block4s, block6s
+}
@@ -0,0 +1,197 @@
+package cidrman
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// SortOrder selects the ordering of SortIPNets and SortCIDRs.
+// SortByAddress and SortByPrefixLength can be combined with SortIPv6First.
+type SortOrder int
+
+const (
+	// SortByAddress sorts numerically by network address, then by prefix length, shorter first.
+	SortByAddress SortOrder = 0
+	// SortByPrefixLength sorts by prefix length, shorter first, then numerically by network address.
+	SortByPrefixLength SortOrder = 1
+	// SortIPv6First sorts IPv6 networks before IPv4 networks, instead of IPv4 first.
+	SortIPv6First SortOrder = 2
+)
+
+// sortKey is the address family, network address and prefix length of a network.
+type sortKey struct {
+	width  uint
+	addr   uint128
+	prefix int
+}
+
+// newSortKey returns the sort key of a network, IPv4-mapped networks like MergeIPNets.
+func newSortKey(n *net.IPNet) (sortKey, error) {
+	ip4, mask, _ := ipv4Network(n)
+	prefix, _ := mask.Size()
+	if ip4 != nil {
+		return sortKey{widthUInt32, uint128{0, uint64(ipv4ToUInt32(ip4))}, prefix}, nil
+	}
+	ip6 := n.IP.To16()
+	if ip6 == nil {
+		return sortKey{}, fmt.Errorf("Invalid IP address: %v", n.IP)
+	}
+	return sortKey{widthUInt128, ip6ToUInt128(ip6), prefix}, nil
+}
+
+// last returns the last address of the network of a key.
+func (k sortKey) last() uint128 {
+	return k.addr.or(hostmask128(k.width - uint(k.prefix)))
+}
+
+// sortKeys sorts a list of indexes by their keys in the given order.
+type sortKeys struct {
+	keys    []sortKey
+	indexes []int
+	order   SortOrder
+}
+
+// Sort interface.
+
+func (s sortKeys) Len() int {
+	return len(s.indexes)
+}
+
+func (s sortKeys) Less(i, j int) bool {
+	lhs := s.keys[s.indexes[i]]
+	rhs := s.keys[s.indexes[j]]
+
+	// By address family
+	if lhs.width != rhs.width {
+		return (lhs.width < rhs.width) != (s.order&SortIPv6First != 0)
+	}
+
+	// Then by prefix length and address, or address and prefix length
+	if s.order&SortByPrefixLength != 0 && lhs.prefix != rhs.prefix {
+		return lhs.prefix < rhs.prefix
+	}
+	if lhs.addr != rhs.addr {
+		return lhs.addr.less(rhs.addr)
+	}
+	return lhs.prefix < rhs.prefix
+}
+
+func (s sortKeys) Swap(i, j int) {
+	s.indexes[i], s.indexes[j] = s.indexes[j], s.indexes[i]
+}
+
+// sortedIndexes returns the indexes of the networks in the given order, equal networks keep their order.
+func sortedIndexes(nets []*net.IPNet, order SortOrder) ([]int, error) {
+	s := sortKeys{
+		keys:    make([]sortKey, len(nets)),
+		indexes: make([]int, len(nets)),
+		order:   order,
+	}
+	for i, net := range nets {
+		key, err := newSortKey(net)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[i] = key
+		s.indexes[i] = i
+	}
+	sort.Stable(s)
+
+	return s.indexes, nil
+}
+
+// SortIPNets returns a sorted copy of a list of mixed IP networks, without merging them.
+// Example:
+//     sorted, err := SortIPNets(nets, SortByPrefixLength|SortIPv6First)
+func SortIPNets(nets []*net.IPNet, order SortOrder) ([]*net.IPNet, error) {
+	if nets == nil {
+		return nil, nil
+	}
+
+	indexes, err := sortedIndexes(nets, order)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]*net.IPNet, len(nets))
+	for i, index := range indexes {
+		sorted[i] = nets[index]
+	}
+	return sorted, nil
+}
+
+// SortCIDRs returns a sorted copy of a list of mixed CIDR blocks, without merging them.
+// The CIDR blocks are kept as written, host bits are ignored when sorting.
+func SortCIDRs(cidrs []string, order SortOrder) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	indexes, err := sortedIndexes(networks, order)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]string, len(cidrs))
+	for i, index := range indexes {
+		sorted[i] = cidrs[index]
+	}
+	return sorted, nil
+}
+
+// DedupIPNets returns a copy of a list of mixed IP networks with exact duplicates removed,
+// without merging or sorting them. The first of the duplicates is kept.
+func DedupIPNets(nets []*net.IPNet) ([]*net.IPNet, error) {
+	if nets == nil {
+		return nil, nil
+	}
+
+	seen := make(map[sortKey]bool)
+	deduped := make([]*net.IPNet, 0, len(nets))
+	for _, net := range nets {
+		key, err := newSortKey(net)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[key] {
+			seen[key] = true
+			deduped = append(deduped, net)
+		}
+	}
+	return deduped, nil
+}
+
+// DedupCIDRs returns a copy of a list of mixed CIDR blocks with exact duplicates removed,
+// without merging or sorting them. CIDR blocks are duplicates when they have the same IP address
+// and prefix length, like "2001:db8::/32" and "2001:0db8::/32", or "::ffff:10.0.0.0/104" and
+// "10.0.0.0/8", but not "10.0.0.5/24" and "10.0.0.0/24". The first of the duplicates is kept, as written.
+func DedupCIDRs(cidrs []string) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+
+	seen := make(map[sortKey]bool)
+	deduped := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		ip, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newSortKey(&net.IPNet{IP: ip, Mask: network.Mask})
+		if err != nil {
+			return nil, err
+		}
+		if !seen[key] {
+			seen[key] = true
+			deduped = append(deduped, cidr)
+		}
+	}
+	return deduped, nil
+}
@@ -0,0 +1,211 @@
+// go test -v -run="TestSortCIDRs|TestDedupCIDRs"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortCIDRs(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Order  SortOrder
+		Output []string
+		Error  bool
+	}
+
+	input := []string{
+		"2001:db8::/32",
+		"10.0.0.0/8",
+		"192.0.2.0/24",
+		"10.0.0.0/16",
+		"2001:db8::/48",
+		"::/0",
+		"9.255.255.255/32",
+		"10.0.0.1/8",
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Order:  SortByAddress,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input:  []string{},
+			Order:  SortByAddress,
+			Output: []string{},
+			Error:  false,
+		},
+		{
+			Input: input,
+			Order: SortByAddress,
+			Output: []string{
+				"9.255.255.255/32",
+				"10.0.0.0/8",
+				"10.0.0.1/8",
+				"10.0.0.0/16",
+				"192.0.2.0/24",
+				"::/0",
+				"2001:db8::/32",
+				"2001:db8::/48",
+			},
+			Error: false,
+		},
+		{
+			Input: input,
+			Order: SortByPrefixLength,
+			Output: []string{
+				"10.0.0.0/8",
+				"10.0.0.1/8",
+				"10.0.0.0/16",
+				"192.0.2.0/24",
+				"9.255.255.255/32",
+				"::/0",
+				"2001:db8::/32",
+				"2001:db8::/48",
+			},
+			Error: false,
+		},
+		{
+			Input: input,
+			Order: SortByAddress | SortIPv6First,
+			Output: []string{
+				"::/0",
+				"2001:db8::/32",
+				"2001:db8::/48",
+				"9.255.255.255/32",
+				"10.0.0.0/8",
+				"10.0.0.1/8",
+				"10.0.0.0/16",
+				"192.0.2.0/24",
+			},
+			Error: false,
+		},
+		{
+			Input: input,
+			Order: SortByPrefixLength | SortIPv6First,
+			Output: []string{
+				"::/0",
+				"2001:db8::/32",
+				"2001:db8::/48",
+				"10.0.0.0/8",
+				"10.0.0.1/8",
+				"10.0.0.0/16",
+				"192.0.2.0/24",
+				"9.255.255.255/32",
+			},
+			Error: false,
+		},
+		{
+			// IPv4-mapped networks sort as IPv4 networks from prefix length 96
+			Input: []string{
+				"10.0.0.0/16",
+				"::ffff:10.0.0.0/104",
+				"::ffff:0.0.0.0/80",
+				"9.0.0.0/8",
+			},
+			Order: SortByPrefixLength,
+			Output: []string{
+				"9.0.0.0/8",
+				"::ffff:10.0.0.0/104",
+				"10.0.0.0/16",
+				"::ffff:0.0.0.0/80",
+			},
+			Error: false,
+		},
+		{
+			Input:  []string{"10.0.0.0/8", "10.0.0.0"},
+			Order:  SortByAddress,
+			Output: nil,
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := SortCIDRs(testCase.Input, testCase.Order)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("SortCIDRs(%#v, %d) failed: %s", testCase.Input, testCase.Order, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("SortCIDRs(%#v, %d) expected error, got: %#v", testCase.Input, testCase.Order, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("SortCIDRs(%#v, %d) expected: %#v, got: %#v", testCase.Input, testCase.Order, testCase.Output, output)
+		}
+	}
+}
+
+func TestDedupCIDRs(t *testing.T) {
+	type TestCase struct {
+		Input  []string
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  nil,
+			Output: nil,
+			Error:  false,
+		},
+		{
+			Input:  []string{},
+			Output: []string{},
+			Error:  false,
+		},
+		{
+			Input: []string{
+				"192.0.2.0/24",
+				"10.0.0.0/8",
+				"2001:0db8::/32",
+				"10.0.0.0/8",
+				"10.0.0.0/16",
+				"2001:db8::/32",
+				"10.1.2.3/8",
+				"::ffff:10.0.0.0/104",
+				"10.1.2.3/8",
+				"10.0.0.5/24",
+				"10.0.0.0/24",
+			},
+			Output: []string{
+				"192.0.2.0/24",
+				"10.0.0.0/8",
+				"2001:0db8::/32",
+				"10.0.0.0/16",
+				"10.1.2.3/8",
+				"10.0.0.5/24",
+				"10.0.0.0/24",
+			},
+			Error: false,
+		},
+		{
+			Input:  []string{"10.0.0.0/8", "10.0.0.0/33"},
+			Output: nil,
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := DedupCIDRs(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("DedupCIDRs(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("DedupCIDRs(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("DedupCIDRs(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
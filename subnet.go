@@ -1,7 +1,51 @@
 package cidrman
 
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// maxSubnets is the largest number of subnets returned by Subnets.
+const maxSubnets = 1 << 20
+
+// subnetCount returns the number of subnets of the given prefix length in a network.
+func subnetCount(n *net.IPNet, prefix int) (*big.Int, error) {
+	ones, bits := n.Mask.Size()
+	if prefix < ones || prefix > bits {
+		return nil, fmt.Errorf("Invalid subnet prefix: %s into /%d", n, prefix)
+	}
+	return big.NewInt(0).Lsh(big.NewInt(1), uint(prefix-ones)), nil
+}
+
+// subnetAt returns the subnet with the given index of the given prefix length in a network.
+func subnetAt(n *net.IPNet, prefix int, index *big.Int) *net.IPNet {
+	_, bits := n.Mask.Size()
+	offset := big.NewInt(0).Lsh(index, uint(bits-prefix))
+	return &net.IPNet{IP: blockOffset(n, offset), Mask: net.CIDRMask(prefix, bits)}
+}
+
 // Subnets divides up CIDR block into smaller subnets based on a specified CIDR prefix.
+// At most 2^20 subnets are returned, larger divisions are an error.
+// Example:
+//     subnets, err := Subnets("192.0.2.0/24", 26)
+//     // [192.0.2.0/26 192.0.2.64/26 192.0.2.128/26 192.0.2.192/26]
 func Subnets(cidr string, prefix int) ([]string, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	count, err := subnetCount(network, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if count.Cmp(big.NewInt(maxSubnets)) > 0 {
+		return nil, fmt.Errorf("Too many subnets: %s into /%d", network, prefix)
+	}
 
-	return nil, nil
+	subnets := make([]string, 0, count.Int64())
+	for i := int64(0); i < count.Int64(); i++ {
+		subnets = append(subnets, subnetAt(network, prefix, big.NewInt(i)).String())
+	}
+	return subnets, nil
 }
@@ -0,0 +1,79 @@
+// go test -v -run="TestSubnets"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubnets(t *testing.T) {
+	type TestCase struct {
+		Input  string
+		Prefix int
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  "192.0.2.0/24",
+			Prefix: 24,
+			Output: []string{"192.0.2.0/24"},
+			Error:  false,
+		},
+		{
+			Input:  "192.0.2.77/24",
+			Prefix: 26,
+			Output: []string{"192.0.2.0/26", "192.0.2.64/26", "192.0.2.128/26", "192.0.2.192/26"},
+			Error:  false,
+		},
+		{
+			Input:  "2001:db8::/46",
+			Prefix: 48,
+			Output: []string{"2001:db8::/48", "2001:db8:1::/48", "2001:db8:2::/48", "2001:db8:3::/48"},
+			Error:  false,
+		},
+		{
+			Input:  "192.0.2.0/24",
+			Prefix: 23,
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "192.0.2.0/24",
+			Prefix: 33,
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "2001:db8::/32",
+			Prefix: 64,
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "192.0.2.0",
+			Prefix: 26,
+			Output: nil,
+			Error:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := Subnets(testCase.Input, testCase.Prefix)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("Subnets(%#v, %d) failed: %s", testCase.Input, testCase.Prefix, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("Subnets(%#v, %d) expected error, got: %#v", testCase.Input, testCase.Prefix, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("Subnets(%#v, %d) expected: %#v, got: %#v", testCase.Input, testCase.Prefix, testCase.Output, output)
+		}
+	}
+}
@@ -407,6 +407,28 @@ func TestSubsetCIDRs(t *testing.T) {
 			},
 			Error:  false,
 		},
+		// Several subset-blocks inside or at the edges of one network-block
+		{
+			Input: []string{
+				"10.0.0.0/8",
+				"2001:db8::/32",
+			},
+			Subset: []string{
+				"10.0.0.0/24",
+				"10.1.0.0/16",
+				"10.255.255.0/24",
+				"2001:db8::/48",
+				"2001:db8:ffff::/48",
+			},
+			Output: []string{
+				"10.0.0.0/24",
+				"10.1.0.0/16",
+				"10.255.255.0/24",
+				"2001:db8::/48",
+				"2001:db8:ffff::/48",
+			},
+			Error: false,
+		},
 		// Mixed blocks
 		{
 			Input:  []string{
@@ -0,0 +1,113 @@
+package cidrman
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// Summary is a supernet of Summarize with the fraction of its addresses covered by the input.
+type Summary struct {
+	Supernet string
+	// Covered is the fraction of the addresses of the supernet in the input, from 0 to 1.
+	Covered float64
+	// Missing is the fraction of the addresses of the supernet not in the input, 1 - Covered.
+	Missing float64
+	// Gaps are the CIDR blocks of the supernet not in the input.
+	Gaps []string
+}
+
+// addressCount returns the number of addresses in a list of disjoint IP networks.
+func addressCount(nets []*net.IPNet) *big.Int {
+	count := big.NewInt(0)
+	for _, network := range nets {
+		ones, bits := network.Mask.Size()
+		count.Add(count, big.NewInt(0).Lsh(big.NewInt(1), uint(bits-ones)))
+	}
+	return count
+}
+
+// summarize returns the summary of the networks inside a supernet.
+func summarize(supernet *net.IPNet, nets []*net.IPNet) (Summary, error) {
+	covered, err := SubsetIPNets(nets, []*net.IPNet{supernet})
+	if err != nil {
+		return Summary{}, err
+	}
+	gaps, err := RemoveIPNets([]*net.IPNet{supernet}, covered)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	fraction, _ := new(big.Rat).SetFrac(addressCount(covered), addressCount([]*net.IPNet{supernet})).Float64()
+	return Summary{
+		Supernet: supernet.String(),
+		Covered:  fraction,
+		Missing:  1 - fraction,
+		Gaps:     ipNets(gaps).toCIDRs(),
+	}, nil
+}
+
+// Summarize rolls up a list of mixed CIDR blocks into the supernets of the given prefix length,
+// like /16 or /48, that are covered for at least the threshold fraction of their addresses.
+// It returns the summaries of those supernets, and the leftovers, the merged CIDR blocks in the
+// supernets below the threshold. CIDR blocks larger than the supernets are fully covered and
+// returned as a summary of their own.
+// The level applies to both IPv4 and IPv6 networks, so the list should normally hold one family.
+// Example:
+//     summaries, leftovers, err := Summarize(cidrs, 16, 0.5)
+//     for _, s := range summaries {
+//         fmt.Printf("%s %.1f%% covered\n", s.Supernet, 100*s.Covered)
+//     }
+func Summarize(cidrs []string, level int, threshold float64) ([]Summary, []string, error) {
+	if level < 0 || level > widthUInt128 {
+		return nil, nil, fmt.Errorf("Invalid summary level: %d", level)
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, nil, fmt.Errorf("Invalid summary threshold: %v", threshold)
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, nil, err
+		}
+		networks = append(networks, network)
+	}
+	merged, err := MergeIPNets(networks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var summaries []Summary
+	var leftovers []*net.IPNet
+	// The merged networks are in order, so the networks of a supernet are consecutive
+	for i := 0; i < len(merged); {
+		ones, bits := merged[i].Mask.Size()
+		if ones <= level {
+			summaries = append(summaries, Summary{Supernet: merged[i].String(), Covered: 1})
+			i++
+			continue
+		}
+
+		mask := net.CIDRMask(level, bits)
+		supernet := &net.IPNet{IP: merged[i].IP.Mask(mask), Mask: mask}
+		j := i + 1
+		for j < len(merged) && supernet.Contains(merged[j].IP) {
+			j++
+		}
+
+		summary, err := summarize(supernet, merged[i:j])
+		if err != nil {
+			return nil, nil, err
+		}
+		if summary.Covered >= threshold {
+			summaries = append(summaries, summary)
+		} else {
+			leftovers = append(leftovers, merged[i:j]...)
+		}
+		i = j
+	}
+
+	return summaries, ipNets(leftovers).toCIDRs(), nil
+}
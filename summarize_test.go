@@ -0,0 +1,116 @@
+// go test -v -run="TestSummarize"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	type TestCase struct {
+		Input     []string
+		Level     int
+		Threshold float64
+		Summaries []Summary
+		Leftovers []string
+		Error     bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:     nil,
+			Level:     16,
+			Threshold: 0.5,
+			Summaries: nil,
+			Leftovers: nil,
+			Error:     false,
+		},
+		{
+			Input: []string{
+				"10.1.0.0/17",
+				"10.1.128.0/18",
+				"10.2.0.0/24",
+				"10.2.1.0/24",
+				"11.0.0.0/8",
+			},
+			Level:     16,
+			Threshold: 0.5,
+			Summaries: []Summary{
+				{Supernet: "10.1.0.0/16", Covered: 0.75, Missing: 0.25, Gaps: []string{"10.1.192.0/18"}},
+				{Supernet: "11.0.0.0/8", Covered: 1, Missing: 0, Gaps: nil},
+			},
+			Leftovers: []string{"10.2.0.0/23"},
+			Error:     false,
+		},
+		{
+			Input: []string{
+				"2001:db8:1::/49",
+				"2001:db8:2::/64",
+			},
+			Level:     48,
+			Threshold: 0,
+			Summaries: []Summary{
+				{Supernet: "2001:db8:1::/48", Covered: 0.5, Missing: 0.5, Gaps: []string{"2001:db8:1:8000::/49"}},
+				{Supernet: "2001:db8:2::/48", Covered: 1.0 / 65536, Missing: 1 - 1.0/65536, Gaps: []string{
+					"2001:db8:2:1::/64",
+					"2001:db8:2:2::/63",
+					"2001:db8:2:4::/62",
+					"2001:db8:2:8::/61",
+					"2001:db8:2:10::/60",
+					"2001:db8:2:20::/59",
+					"2001:db8:2:40::/58",
+					"2001:db8:2:80::/57",
+					"2001:db8:2:100::/56",
+					"2001:db8:2:200::/55",
+					"2001:db8:2:400::/54",
+					"2001:db8:2:800::/53",
+					"2001:db8:2:1000::/52",
+					"2001:db8:2:2000::/51",
+					"2001:db8:2:4000::/50",
+					"2001:db8:2:8000::/49",
+				}},
+			},
+			Leftovers: nil,
+			Error:     false,
+		},
+		{
+			Input:     []string{"10.0.0.0/8"},
+			Level:     129,
+			Threshold: 0.5,
+			Error:     true,
+		},
+		{
+			Input:     []string{"10.0.0.0/8"},
+			Level:     16,
+			Threshold: 50,
+			Error:     true,
+		},
+		{
+			Input:     []string{"10.0.0.0/33"},
+			Level:     16,
+			Threshold: 0.5,
+			Error:     true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		summaries, leftovers, err := Summarize(testCase.Input, testCase.Level, testCase.Threshold)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("Summarize(%#v, %d, %v) failed: %s", testCase.Input, testCase.Level, testCase.Threshold, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("Summarize(%#v, %d, %v) expected error, got: %#v", testCase.Input, testCase.Level, testCase.Threshold, summaries)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Summaries, summaries) {
+			t.Errorf("Summarize(%#v, %d, %v) expected: %#v, got: %#v", testCase.Input, testCase.Level, testCase.Threshold, testCase.Summaries, summaries)
+		}
+		if !reflect.DeepEqual(testCase.Leftovers, leftovers) {
+			t.Errorf("Summarize(%#v, %d, %v) expected leftovers: %#v, got: %#v", testCase.Input, testCase.Level, testCase.Threshold, testCase.Leftovers, leftovers)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Parsing of nmap-style target expressions like "10.0-3.1-254.1", "192.168.*.1" and
+// "10.0.0.0/24,!10.0.0.5" into the minimal list of CIDR blocks.
+
+package cidrman
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// octetRange is an inclusive range of values for one IPv4 octet.
+type octetRange struct {
+	lo uint32
+	hi uint32
+}
+
+// parseOctet parses a single octet value.
+func parseOctet(s string) (uint32, error) {
+	value, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid octet: %s", s)
+	}
+	return uint32(value), nil
+}
+
+// parseOctetRange parses an octet expression: "n", "n-m", "-m", "n-" or "*".
+func parseOctetRange(s string) (octetRange, error) {
+	if s == "*" {
+		return octetRange{lo: 0, hi: 255}, nil
+	}
+
+	r := octetRange{lo: 0, hi: 255}
+	var err error
+	i := strings.Index(s, "-")
+	if i < 0 {
+		r.lo, err = parseOctet(s)
+		r.hi = r.lo
+		return r, err
+	}
+	if i > 0 {
+		if r.lo, err = parseOctet(s[:i]); err != nil {
+			return r, err
+		}
+	}
+	if i < len(s)-1 {
+		if r.hi, err = parseOctet(s[i+1:]); err != nil {
+			return r, err
+		}
+	}
+	if r.hi < r.lo {
+		return r, fmt.Errorf("Invalid octet range: %s", s)
+	}
+	return r, nil
+}
+
+// expandOctets appends the CIDR blocks covering all addresses matched by the octet ranges.
+// Trailing full octets (0-255) are folded into a single address range per combination
+// of the leading octets, so only the leading octets are enumerated.
+func expandOctets(octets []octetRange, cidrs *[]*net.IPNet) error {
+	k := len(octets) - 1
+	for k > 0 && octets[k].lo == 0 && octets[k].hi == 255 {
+		k--
+	}
+	shift := uint(8 * (len(octets) - 1 - k))
+
+	var expand func(i int, addr uint32) error
+	expand = func(i int, addr uint32) error {
+		if i == k {
+			lo := (addr | octets[k].lo) << shift
+			hi := (addr|octets[k].hi)<<shift | hostmask4(widthUInt32-shift)
+			return splitRange4(0, 0, lo, hi, cidrs)
+		}
+		for v := octets[i].lo; v <= octets[i].hi; v++ {
+			if err := expand(i+1, (addr|v)<<8); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return expand(0, 0)
+}
+
+// parseTarget parses a single target: a CIDR block, an IPv6 address or an IPv4 octet expression.
+func parseTarget(target string, cidrs *[]*net.IPNet) error {
+	if strings.Contains(target, "/") {
+		_, network, err := net.ParseCIDR(target)
+		if err != nil {
+			return err
+		}
+		*cidrs = append(*cidrs, network)
+		return nil
+	}
+
+	if strings.Contains(target, ":") {
+		ip := net.ParseIP(target)
+		if ip == nil {
+			return fmt.Errorf("Invalid IP address: %s", target)
+		}
+		*cidrs = append(*cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)})
+		return nil
+	}
+
+	fields := strings.Split(target, ".")
+	if len(fields) != net.IPv4len {
+		return fmt.Errorf("Invalid target: %s", target)
+	}
+	octets := make([]octetRange, net.IPv4len)
+	for i, field := range fields {
+		r, err := parseOctetRange(field)
+		if err != nil {
+			return fmt.Errorf("Invalid target: %s: %s", target, err)
+		}
+		octets[i] = r
+	}
+	return expandOctets(octets, cidrs)
+}
+
+// ParseTargets parses nmap-style target expressions and returns the smallest possible list of IPNets.
+// Targets are separated by commas or whitespace and can be CIDR blocks ("10.0.0.0/24"), IPv6 addresses
+// or IPv4 addresses where each octet is a value, a range ("1-254", "-100", "200-") or a wildcard ("*").
+// Targets prefixed with "!" are excluded from the result.
+// Example:
+//     nets, err := ParseTargets("10.0-3.1-254.1, 192.168.*.1, 10.0.0.0/24, !10.0.0.5")
+func ParseTargets(expr string) ([]*net.IPNet, error) {
+	targets := strings.FieldsFunc(expr, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	var networks []*net.IPNet
+	var excludes []*net.IPNet
+	for _, target := range targets {
+		var err error
+		if strings.HasPrefix(target, "!") {
+			err = parseTarget(target[1:], &excludes)
+		} else {
+			err = parseTarget(target, &networks)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(networks) == 0 {
+		return make([]*net.IPNet, 0), nil
+	}
+
+	networks, err := MergeIPNets(networks)
+	if err != nil {
+		return nil, err
+	}
+	return RemoveIPNets(networks, excludes)
+}
+
+// TargetsToCIDRs parses nmap-style target expressions and returns the smallest possible list of CIDRs.
+func TargetsToCIDRs(expr string) ([]string, error) {
+	nets, err := ParseTargets(expr)
+	if err != nil {
+		return nil, err
+	}
+	// Handle the situation where all targets were excluded
+	if len(nets) == 0 {
+		return make([]string, 0), nil
+	}
+
+	return ipNets(nets).toCIDRs(), nil
+}
@@ -0,0 +1,164 @@
+// go test -v -run="TestTargetsToCIDRs"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTargetsToCIDRs(t *testing.T) {
+	type TestCase struct {
+		Input  string
+		Output []string
+		Error  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input:  "",
+			Output: []string{},
+			Error:  false,
+		},
+		{
+			Input:  "10.0.0",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "10.0.0.256",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "10.0.5-3.1",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input:  "2001:db8::zz",
+			Output: nil,
+			Error:  true,
+		},
+		{
+			Input: "10.0.0.1",
+			Output: []string{
+				"10.0.0.1/32",
+			},
+			Error: false,
+		},
+		{
+			Input: "10.0.0.*",
+			Output: []string{
+				"10.0.0.0/24",
+			},
+			Error: false,
+		},
+		{
+			Input: "*.*.*.*",
+			Output: []string{
+				"0.0.0.0/0",
+			},
+			Error: false,
+		},
+		{
+			Input: "10.0-3.*.*",
+			Output: []string{
+				"10.0.0.0/14",
+			},
+			Error: false,
+		},
+		{
+			Input: "10.0.0.1-254",
+			Output: []string{
+				"10.0.0.1/32",
+				"10.0.0.2/31",
+				"10.0.0.4/30",
+				"10.0.0.8/29",
+				"10.0.0.16/28",
+				"10.0.0.32/27",
+				"10.0.0.64/26",
+				"10.0.0.128/26",
+				"10.0.0.192/27",
+				"10.0.0.224/28",
+				"10.0.0.240/29",
+				"10.0.0.248/30",
+				"10.0.0.252/31",
+				"10.0.0.254/32",
+			},
+			Error: false,
+		},
+		{
+			Input: "10.0-3.1.1",
+			Output: []string{
+				"10.0.1.1/32",
+				"10.1.1.1/32",
+				"10.2.1.1/32",
+				"10.3.1.1/32",
+			},
+			Error: false,
+		},
+		{
+			Input: "192.168.*.0-255, 192.168.4.0-1",
+			Output: []string{
+				"192.168.0.0/16",
+			},
+			Error: false,
+		},
+		{
+			Input: "10.0.0.200-, 10.0.0.-7",
+			Output: []string{
+				"10.0.0.0/29",
+				"10.0.0.200/29",
+				"10.0.0.208/28",
+				"10.0.0.224/27",
+			},
+			Error: false,
+		},
+		{
+			Input: "10.0.0.0/24,!10.0.0.5",
+			Output: []string{
+				"10.0.0.0/30",
+				"10.0.0.4/32",
+				"10.0.0.6/31",
+				"10.0.0.8/29",
+				"10.0.0.16/28",
+				"10.0.0.32/27",
+				"10.0.0.64/26",
+				"10.0.0.128/25",
+			},
+			Error: false,
+		},
+		{
+			Input:  "10.0.0.0/24 !10.0.0.*",
+			Output: []string{},
+			Error:  false,
+		},
+		// IPv6 tests
+		{
+			Input: "2001:db8::1, 2001:db8:1::/48, !2001:db8:1::/49",
+			Output: []string{
+				"2001:db8::1/128",
+				"2001:db8:1:8000::/49",
+			},
+			Error: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output, err := TargetsToCIDRs(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("TargetsToCIDRs(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("TargetsToCIDRs(%#v) expected error, got: %#v", testCase.Input, output)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Output, output) {
+			t.Errorf("TargetsToCIDRs(%#v) expected: %#v, got: %#v", testCase.Input, testCase.Output, output)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package cidrman
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+)
+
+// PrefixNode is an input CIDR block of a PrefixTree with the input CIDR blocks directly inside it.
+type PrefixNode struct {
+	Net *net.IPNet
+	// Index is the index of the CIDR block in the input.
+	Index    int
+	Children []*PrefixNode
+	// Gaps are the CIDR blocks of Net outside the children, nil for a leaf.
+	Gaps []*net.IPNet
+	// Utilization is the fraction of the addresses of Net inside the children, from 0 to 1.
+	Utilization float64
+}
+
+// PrefixTree is the containment hierarchy of an unmerged list of CIDR blocks.
+// Children are ordered like cidrBlock4s and cidrBlock6s, by last and then first address.
+// Exact duplicates are nested inside the first of them.
+type PrefixTree struct {
+	// Roots are the CIDR blocks not inside another, IPv4 before IPv6.
+	Roots []*PrefixNode
+}
+
+// contains reports whether the node contains the network.
+func (n *PrefixNode) contains(network *net.IPNet) bool {
+	ones, bits := n.Net.Mask.Size()
+	nones, nbits := network.Mask.Size()
+	return bits == nbits && ones <= nones && n.Net.Contains(network.IP)
+}
+
+// insertPrefixNode inserts a node into a list of sibling nodes, in the order of cidrBlock4s.Less.
+// The trailing siblings inside the node become its children, or, if the last sibling contains
+// the node, the node is inserted into its children.
+func insertPrefixNode(siblings []*PrefixNode, node *PrefixNode) []*PrefixNode {
+	i := len(siblings)
+	for i > 0 && node.contains(siblings[i-1].Net) && !siblings[i-1].contains(node.Net) {
+		i--
+	}
+	if i < len(siblings) {
+		node.Children = append(node.Children, siblings[i:]...)
+		return append(siblings[:i], node)
+	}
+	if i > 0 && siblings[i-1].contains(node.Net) {
+		siblings[i-1].Children = insertPrefixNode(siblings[i-1].Children, node)
+		return siblings
+	}
+	return append(siblings, node)
+}
+
+// utilize sets the gaps and utilization of a node and its children.
+func (n *PrefixNode) utilize() error {
+	if len(n.Children) == 0 {
+		return nil
+	}
+
+	children := make([]*net.IPNet, len(n.Children))
+	for i, child := range n.Children {
+		if err := child.utilize(); err != nil {
+			return err
+		}
+		children[i] = child.Net
+	}
+	gaps, err := RemoveIPNets([]*net.IPNet{n.Net}, children)
+	if err != nil {
+		return err
+	}
+	n.Gaps = gaps
+	n.Utilization, _ = new(big.Rat).SetFrac(addressCount(children), addressCount([]*net.IPNet{n.Net})).Float64()
+	return nil
+}
+
+// NewPrefixTree returns the containment hierarchy of a list of mixed CIDR blocks, which input
+// CIDR blocks sit inside which others, without merging them.
+// Example:
+//     tree, err := NewPrefixTree([]string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"})
+//     fmt.Print(tree.ASCII())
+func NewPrefixTree(cidrs []string) (*PrefixTree, error) {
+	var block4s cidrBlock4s
+	var block6s cidrBlock6s
+	nodes := make(map[interface{}]*PrefixNode)
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ip4, mask, mapped := ipv4Network(network)
+		if ip4 != nil && mapped {
+			// IPv4-mapped networks like MergeIPNets
+			network = &net.IPNet{IP: ip4, Mask: mask}
+		}
+		node := &PrefixNode{Net: network, Index: i}
+		if ip4 != nil {
+			block := newBlock4(ip4, mask)
+			block4s = append(block4s, block)
+			nodes[block] = node
+		} else {
+			block := newBlock6(network.IP.To16(), network.Mask)
+			block6s = append(block6s, block)
+			nodes[block] = node
+		}
+	}
+	sort.Stable(block4s)
+	sort.Stable(block6s)
+
+	tree := &PrefixTree{}
+	var roots6 []*PrefixNode
+	for _, block := range block4s {
+		tree.Roots = insertPrefixNode(tree.Roots, nodes[block])
+	}
+	for _, block := range block6s {
+		roots6 = insertPrefixNode(roots6, nodes[block])
+	}
+	tree.Roots = append(tree.Roots, roots6...)
+
+	for _, root := range tree.Roots {
+		if err := root.utilize(); err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// describe returns the index, utilization and gaps of the node.
+func (n *PrefixNode) describe() []string {
+	lines := []string{fmt.Sprintf("input %d", n.Index)}
+	if len(n.Children) > 0 {
+		lines = append(lines, fmt.Sprintf("%.1f%% used", 100*n.Utilization))
+		for _, gap := range n.Gaps {
+			lines = append(lines, "gap "+gap.String())
+		}
+	}
+	return lines
+}
+
+// writeASCII writes the node and its children, indented by the prefix.
+func (n *PrefixNode) writeASCII(buf *bytes.Buffer, prefix string, last bool, root bool) {
+	childPrefix := prefix
+	if !root {
+		if last {
+			buf.WriteString(prefix + "`-- ")
+			childPrefix = prefix + "    "
+		} else {
+			buf.WriteString(prefix + "|-- ")
+			childPrefix = prefix + "|   "
+		}
+	}
+	buf.WriteString(n.Net.String() + " (" + strings.Join(n.describe(), ", ") + ")\n")
+	for i, child := range n.Children {
+		child.writeASCII(buf, childPrefix, i == len(n.Children)-1, false)
+	}
+}
+
+// ASCII returns the tree as indented text, one CIDR block per line.
+// Example:
+//     10.0.0.0/8 (input 0, 0.4% used, gap 10.0.0.0/16, gap 10.2.0.0/15, ...)
+//     `-- 10.1.0.0/16 (input 1)
+func (t *PrefixTree) ASCII() string {
+	var buf bytes.Buffer
+	for _, root := range t.Roots {
+		root.writeASCII(&buf, "", true, true)
+	}
+	return buf.String()
+}
+
+// writeDOT writes the node and its children as Graphviz nodes and edges.
+func (n *PrefixNode) writeDOT(buf *bytes.Buffer) {
+	label := append([]string{n.Net.String()}, n.describe()...)
+	fmt.Fprintf(buf, "\tn%d [label=\"%s\"];\n", n.Index, strings.Join(label, "\\n"))
+	for _, child := range n.Children {
+		fmt.Fprintf(buf, "\tn%d -> n%d;\n", n.Index, child.Index)
+	}
+	for _, child := range n.Children {
+		child.writeDOT(buf)
+	}
+}
+
+// DOT returns the tree as a Graphviz DOT digraph, with an edge from each CIDR block to the blocks inside it.
+// Example:
+//     ioutil.WriteFile("tree.dot", []byte(tree.DOT()), 0644)  // dot -Tsvg tree.dot
+func (t *PrefixTree) DOT() string {
+	var buf bytes.Buffer
+	buf.WriteString("digraph prefixes {\n\tnode [shape=box];\n")
+	for _, root := range t.Roots {
+		root.writeDOT(&buf)
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
@@ -0,0 +1,106 @@
+// go test -v -run="TestPrefixTree"
+
+package cidrman
+
+import (
+	"testing"
+)
+
+func TestPrefixTree(t *testing.T) {
+	type TestCase struct {
+		Input []string
+		ASCII string
+		DOT   string
+		Error bool
+	}
+
+	testCases := []TestCase{
+		{
+			Input: nil,
+			ASCII: "",
+			DOT:   "digraph prefixes {\n\tnode [shape=box];\n}\n",
+			Error: false,
+		},
+		{
+			Input: []string{
+				"10.0.0.0/22",
+				"10.0.3.0/24",
+				"2001:db8::/32",
+				"10.0.0.0/24",
+				"10.0.0.128/25",
+				"10.0.3.0/24",
+				"192.0.2.0/24",
+			},
+			ASCII: "" +
+				"10.0.0.0/22 (input 0, 50.0% used, gap 10.0.1.0/24, gap 10.0.2.0/24)\n" +
+				"|-- 10.0.0.0/24 (input 3, 50.0% used, gap 10.0.0.0/25)\n" +
+				"|   `-- 10.0.0.128/25 (input 4)\n" +
+				"`-- 10.0.3.0/24 (input 1, 100.0% used)\n" +
+				"    `-- 10.0.3.0/24 (input 5)\n" +
+				"192.0.2.0/24 (input 6)\n" +
+				"2001:db8::/32 (input 2)\n",
+			DOT: "digraph prefixes {\n" +
+				"\tnode [shape=box];\n" +
+				"\tn0 [label=\"10.0.0.0/22\\ninput 0\\n50.0% used\\ngap 10.0.1.0/24\\ngap 10.0.2.0/24\"];\n" +
+				"\tn0 -> n3;\n" +
+				"\tn0 -> n1;\n" +
+				"\tn3 [label=\"10.0.0.0/24\\ninput 3\\n50.0% used\\ngap 10.0.0.0/25\"];\n" +
+				"\tn3 -> n4;\n" +
+				"\tn4 [label=\"10.0.0.128/25\\ninput 4\"];\n" +
+				"\tn1 [label=\"10.0.3.0/24\\ninput 1\\n100.0% used\"];\n" +
+				"\tn1 -> n5;\n" +
+				"\tn5 [label=\"10.0.3.0/24\\ninput 5\"];\n" +
+				"\tn6 [label=\"192.0.2.0/24\\ninput 6\"];\n" +
+				"\tn2 [label=\"2001:db8::/32\\ninput 2\"];\n" +
+				"}\n",
+			Error: false,
+		},
+		{
+			// IPv4-mapped networks are IPv4 networks from prefix length 96
+			Input: []string{
+				"192.0.2.0/24",
+				"::ffff:10.0.0.0/104",
+				"10.0.0.0/9",
+				"::ffff:0.0.0.0/80",
+			},
+			ASCII: "" +
+				"10.0.0.0/8 (input 1, 50.0% used, gap 10.128.0.0/9)\n" +
+				"`-- 10.0.0.0/9 (input 2)\n" +
+				"192.0.2.0/24 (input 0)\n" +
+				"::/80 (input 3)\n",
+			DOT: "digraph prefixes {\n" +
+				"\tnode [shape=box];\n" +
+				"\tn1 [label=\"10.0.0.0/8\\ninput 1\\n50.0% used\\ngap 10.128.0.0/9\"];\n" +
+				"\tn1 -> n2;\n" +
+				"\tn2 [label=\"10.0.0.0/9\\ninput 2\"];\n" +
+				"\tn0 [label=\"192.0.2.0/24\\ninput 0\"];\n" +
+				"\tn3 [label=\"::/80\\ninput 3\"];\n" +
+				"}\n",
+			Error: false,
+		},
+		{
+			Input: []string{"10.0.0.0/8", "10.0.0.0/33"},
+			Error: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := NewPrefixTree(testCase.Input)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("NewPrefixTree(%#v) failed: %s", testCase.Input, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("NewPrefixTree(%#v) expected error, got: %#v", testCase.Input, tree)
+			continue
+		}
+		if ascii := tree.ASCII(); ascii != testCase.ASCII {
+			t.Errorf("NewPrefixTree(%#v).ASCII() expected:\n%s\ngot:\n%s", testCase.Input, testCase.ASCII, ascii)
+		}
+		if dot := tree.DOT(); dot != testCase.DOT {
+			t.Errorf("NewPrefixTree(%#v).DOT() expected:\n%s\ngot:\n%s", testCase.Input, testCase.DOT, dot)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cidrman
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// VLSMRequest is a named subnet with the number of hosts it needs.
+type VLSMRequest struct {
+	Name  string
+	Hosts int
+}
+
+// VLSMSubnet is a subnet allocated by VLSM.
+type VLSMSubnet struct {
+	Name  string
+	Hosts int
+	CIDR  string
+}
+
+// vlsmPrefix returns the prefix length of the smallest subnet with room for the hosts.
+// IPv4 subnets lose the network and broadcast address, so a single host gets a /30,
+// except point-to-point links of 2 hosts, which are /31 (RFC 3021) and /127 for IPv6 (RFC 6164).
+func vlsmPrefix(hosts int, bits int) (int, error) {
+	if hosts < 1 {
+		return 0, fmt.Errorf("Invalid VLSM host count: %d", hosts)
+	}
+	if hosts == 2 {
+		return bits - 1, nil
+	}
+
+	addresses := big.NewInt(int64(hosts))
+	if bits == 8*net.IPv4len {
+		addresses.Add(addresses, big.NewInt(2))
+	}
+	// The smallest power of two not below the number of addresses
+	hostBits := addresses.Sub(addresses, big.NewInt(1)).BitLen()
+	if hostBits > bits {
+		return 0, fmt.Errorf("Invalid VLSM host count: %d", hosts)
+	}
+	return bits - hostBits, nil
+}
+
+// VLSM turns the host counts of a list of requests into prefix lengths and packs the subnets,
+// largest first, into the parent CIDR block. It returns the subnets in the order of the
+// requests, and the leftover free space of the parent as CIDR blocks.
+// Example:
+//     subnets, free, err := VLSM("192.0.2.0/24", []VLSMRequest{
+//         {"LAN A", 100}, {"LAN B", 50}, {"link 1", 2}, {"link 2", 2},
+//     })
+//     // LAN A 192.0.2.0/25, LAN B 192.0.2.128/26, link 1 192.0.2.192/31, link 2 192.0.2.194/31
+//     // free [192.0.2.196/30 192.0.2.200/29 192.0.2.208/28 192.0.2.224/27]
+func VLSM(parent string, requests []VLSMRequest) ([]VLSMSubnet, []string, error) {
+	_, network, err := net.ParseCIDR(parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	ones, bits := network.Mask.Size()
+
+	prefixes := make([]int, len(requests))
+	order := make([]int, len(requests))
+	for i, request := range requests {
+		prefixes[i], err = vlsmPrefix(request.Hosts, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return prefixes[order[i]] < prefixes[order[j]]
+	})
+
+	// Largest first, every subnet is aligned right after the previous one and fits if it starts inside the parent
+	subnets := make([]VLSMSubnet, len(requests))
+	allocated := make([]string, 0, len(requests))
+	size := big.NewInt(0).Lsh(big.NewInt(1), uint(bits-ones))
+	offset := big.NewInt(0)
+	for _, i := range order {
+		if prefixes[i] < ones || offset.Cmp(size) >= 0 {
+			return nil, nil, fmt.Errorf("VLSM does not fit: %s needs a /%d in %s", requests[i].Name, prefixes[i], network)
+		}
+		subnet := &net.IPNet{IP: blockOffset(network, offset), Mask: net.CIDRMask(prefixes[i], bits)}
+		subnets[i] = VLSMSubnet{Name: requests[i].Name, Hosts: requests[i].Hosts, CIDR: subnet.String()}
+		allocated = append(allocated, subnet.String())
+		offset.Add(offset, big.NewInt(0).Lsh(big.NewInt(1), uint(bits-prefixes[i])))
+	}
+
+	free, err := RemoveCIDRs([]string{network.String()}, allocated)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subnets, free, nil
+}
@@ -0,0 +1,117 @@
+// go test -v -run="TestVLSM"
+
+package cidrman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVLSM(t *testing.T) {
+	type TestCase struct {
+		Parent   string
+		Requests []VLSMRequest
+		Subnets  []VLSMSubnet
+		Free     []string
+		Error    bool
+	}
+
+	testCases := []TestCase{
+		{
+			Parent:   "192.0.2.0/24",
+			Requests: nil,
+			Subnets:  []VLSMSubnet{},
+			Free:     []string{"192.0.2.0/24"},
+			Error:    false,
+		},
+		{
+			Parent: "192.0.2.0/24",
+			Requests: []VLSMRequest{
+				{"link 1", 2},
+				{"LAN B", 50},
+				{"LAN A", 100},
+				{"link 2", 2},
+				{"server", 1},
+			},
+			Subnets: []VLSMSubnet{
+				{"link 1", 2, "192.0.2.196/31"},
+				{"LAN B", 50, "192.0.2.128/26"},
+				{"LAN A", 100, "192.0.2.0/25"},
+				{"link 2", 2, "192.0.2.198/31"},
+				{"server", 1, "192.0.2.192/30"},
+			},
+			Free:  []string{"192.0.2.200/29", "192.0.2.208/28", "192.0.2.224/27"},
+			Error: false,
+		},
+		{
+			// 126 hosts fill a /25, 127 hosts need a /24
+			Parent: "10.0.0.0/23",
+			Requests: []VLSMRequest{
+				{"LAN A", 126},
+				{"LAN B", 127},
+			},
+			Subnets: []VLSMSubnet{
+				{"LAN A", 126, "10.0.1.0/25"},
+				{"LAN B", 127, "10.0.0.0/24"},
+			},
+			Free:  []string{"10.0.1.128/25"},
+			Error: false,
+		},
+		{
+			Parent: "2001:db8::/120",
+			Requests: []VLSMRequest{
+				{"link", 2},
+				{"LAN", 128},
+			},
+			Subnets: []VLSMSubnet{
+				{"link", 2, "2001:db8::80/127"},
+				{"LAN", 128, "2001:db8::/121"},
+			},
+			Free:  []string{"2001:db8::82/127", "2001:db8::84/126", "2001:db8::88/125", "2001:db8::90/124", "2001:db8::a0/123", "2001:db8::c0/122"},
+			Error: false,
+		},
+		{
+			Parent: "192.0.2.0/24",
+			Requests: []VLSMRequest{
+				{"LAN A", 500},
+			},
+			Error: true,
+		},
+		{
+			Parent: "192.0.2.0/24",
+			Requests: []VLSMRequest{
+				{"LAN A", 120},
+				{"LAN B", 120},
+				{"link", 2},
+			},
+			Error: true,
+		},
+		{
+			Parent: "192.0.2.0/24",
+			Requests: []VLSMRequest{
+				{"LAN A", 0},
+			},
+			Error: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		subnets, free, err := VLSM(testCase.Parent, testCase.Requests)
+		if err != nil {
+			if !testCase.Error {
+				t.Errorf("VLSM(%#v, %#v) failed: %s", testCase.Parent, testCase.Requests, err.Error())
+			}
+			continue
+		}
+		if testCase.Error {
+			t.Errorf("VLSM(%#v, %#v) expected error, got: %#v", testCase.Parent, testCase.Requests, subnets)
+			continue
+		}
+		if !reflect.DeepEqual(testCase.Subnets, subnets) {
+			t.Errorf("VLSM(%#v, %#v) expected: %#v, got: %#v", testCase.Parent, testCase.Requests, testCase.Subnets, subnets)
+		}
+		if !reflect.DeepEqual(testCase.Free, free) {
+			t.Errorf("VLSM(%#v, %#v) expected free: %#v, got: %#v", testCase.Parent, testCase.Requests, testCase.Free, free)
+		}
+	}
+}
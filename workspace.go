@@ -0,0 +1,409 @@
+// Allocation-free merge, remove and subset of IP networks.
+// The blocks are kept by value in reusable slices and the output networks are appended to a
+// caller-provided slice, with their IP addresses and masks stored in a reusable arena.
+
+package cidrman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"net"
+	"sort"
+)
+
+// workspaceArenaSize is the initial size of the arena of a workspace.
+const workspaceArenaSize = 4096
+
+// uint128 is an unsigned 128-bit integer, used as address by value instead of big.Int.
+type uint128 struct {
+	hi, lo uint64
+}
+
+// less reports whether u < v.
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || (u.hi == v.hi && u.lo < v.lo)
+}
+
+// addOne returns u + 1, wrapping around at the maximum value.
+func (u uint128) addOne() uint128 {
+	lo, carry := bits.Add64(u.lo, 1, 0)
+	return uint128{u.hi + carry, lo}
+}
+
+// subOne returns u - 1, wrapping around at zero.
+func (u uint128) subOne() uint128 {
+	lo, borrow := bits.Sub64(u.lo, 1, 0)
+	return uint128{u.hi - borrow, lo}
+}
+
+// or returns u | v.
+func (u uint128) or(v uint128) uint128 {
+	return uint128{u.hi | v.hi, u.lo | v.lo}
+}
+
+// isMax reports whether u is the maximum 128-bit value.
+func (u uint128) isMax() bool {
+	return u.hi == math.MaxUint64 && u.lo == math.MaxUint64
+}
+
+// trailingZeros returns the number of trailing zero bits in u, 128 for u == 0.
+func (u uint128) trailingZeros() uint {
+	if u.lo == 0 {
+		return 64 + uint(bits.TrailingZeros64(u.hi))
+	}
+	return uint(bits.TrailingZeros64(u.lo))
+}
+
+// hostmask128 returns the hostmask with the given number of host bits.
+func hostmask128(hostBits uint) uint128 {
+	if hostBits >= 64 {
+		return uint128{1<<(hostBits-64) - 1, math.MaxUint64}
+	}
+	return uint128{0, 1<<hostBits - 1}
+}
+
+// ipToUInt128 converts an IP address to an unsigned 128-bit integer and returns its width,
+// 32 for IPv4 and 128 for IPv6 addresses. The width is 0 for an invalid address.
+func ipToUInt128(ip net.IP) (uint128, uint) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return uint128{0, uint64(ipv4ToUInt32(ip4))}, widthUInt32
+	}
+	if ip6 := ip.To16(); ip6 != nil {
+		return ip6ToUInt128(ip6), widthUInt128
+	}
+	return uint128{}, 0
+}
+
+// ip6ToUInt128 converts a 16-byte IP address to an unsigned 128-bit integer, also an IPv4-mapped one.
+func ip6ToUInt128(ip6 net.IP) uint128 {
+	return uint128{binary.BigEndian.Uint64(ip6), binary.BigEndian.Uint64(ip6[8:])}
+}
+
+// uint128ToIP converts an address of the given width to an IP address.
+func uint128ToIP(addr uint128, width uint) net.IP {
+	if width == widthUInt32 {
+		return uint32ToIPV4(uint32(addr.lo))
+	}
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip, addr.hi)
+	binary.BigEndian.PutUint64(ip[8:], addr.lo)
+	return ip
+}
+
+// cidrBlockValue is an IPv4 or IPv6 CIDR block by value, IPv4 addresses use the low bits only.
+type cidrBlockValue struct {
+	first uint128
+	last  uint128
+}
+
+// cidrBlockValues sorts by first address, then by last address.
+type cidrBlockValues []cidrBlockValue
+
+// Sort interface.
+
+func (c cidrBlockValues) Len() int {
+	return len(c)
+}
+
+func (c cidrBlockValues) Less(i, j int) bool {
+	if c[i].first != c[j].first {
+		return c[i].first.less(c[j].first)
+	}
+	return c[i].last.less(c[j].last)
+}
+
+func (c cidrBlockValues) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+// coalesce sorts the blocks and coalesces overlapping and adjacent blocks in place.
+// A pointer is sorted to avoid allocating an interface value for the slice.
+func (c *cidrBlockValues) coalesce() {
+	sort.Sort(c)
+
+	blocks := *c
+	n := 0
+	for _, block := range blocks {
+		if n > 0 && (blocks[n-1].last.isMax() || !blocks[n-1].last.addOne().less(block.first)) {
+			if blocks[n-1].last.less(block.last) {
+				blocks[n-1].last = block.last
+			}
+			continue
+		}
+		blocks[n] = block
+		n++
+	}
+	*c = blocks[:n]
+}
+
+// blockEdge is the start or the end (inclusive) of a block in a sweep over blocks, with a value
+// of the block like a reference count or the index of its list.
+type blockEdge struct {
+	addr  uint128
+	value int
+	start bool
+}
+
+// blockEdges sorts by address, starts before ends at the same address.
+type blockEdges []blockEdge
+
+// appendBlock appends the start and the end of a block with its value.
+func (c blockEdges) appendBlock(block cidrBlockValue, value int) blockEdges {
+	return append(c, blockEdge{block.first, value, true}, blockEdge{block.last, value, false})
+}
+
+// Sort interface.
+
+func (c blockEdges) Len() int {
+	return len(c)
+}
+
+func (c blockEdges) Less(i, j int) bool {
+	if c[i].addr != c[j].addr {
+		return c[i].addr.less(c[j].addr)
+	}
+	// Both blocks include the address
+	return c[i].start && !c[j].start
+}
+
+func (c blockEdges) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+// Workspace holds the scratch buffers of allocation-free merge, remove and subset operations.
+// Reusing a workspace makes the operations allocation-free once its buffers have grown to the size
+// of the input and output. The IP addresses and masks of the output networks are stored in the
+// arena of the workspace and stay valid until Reset is called.
+// A workspace must not be used concurrently.
+// Example:
+//     ws := NewWorkspace()
+//     for _, nets := range lists {
+//         ws.Reset()
+//         merged, err = ws.MergeIPNets(merged[:0], nets)
+//     }
+type Workspace struct {
+	block4s cidrBlockValues
+	block6s cidrBlockValues
+	other4s cidrBlockValues
+	other6s cidrBlockValues
+	arena   []byte
+	// allocated is the number of arena bytes handed out since Reset, across replaced arenas
+	allocated int
+}
+
+// NewWorkspace returns a new, empty workspace.
+func NewWorkspace() *Workspace {
+	return &Workspace{arena: make([]byte, 0, workspaceArenaSize)}
+}
+
+// Reset makes the arena reusable. The networks returned since the previous Reset must no longer be used.
+func (ws *Workspace) Reset() {
+	ws.arena = ws.arena[:0]
+	ws.allocated = 0
+}
+
+// alloc returns n bytes from the arena. A full arena is replaced by one large enough for
+// everything allocated since Reset, the networks already returned keep referring to the old arena.
+func (ws *Workspace) alloc(n int) []byte {
+	ws.allocated += n
+	used := len(ws.arena)
+	if used+n > cap(ws.arena) {
+		ws.arena = make([]byte, 0, 2*ws.allocated)
+		used = 0
+	}
+	ws.arena = ws.arena[:used+n]
+	return ws.arena[used : used+n : used+n]
+}
+
+// appendIPNet appends the network of the address and prefix to dst.
+func (ws *Workspace) appendIPNet(dst []net.IPNet, addr uint128, prefix, width uint) []net.IPNet {
+	size := int(width / 8)
+	b := ws.alloc(2 * size)
+	ip, mask := b[:size:size], b[size:]
+	if width == widthUInt32 {
+		binary.BigEndian.PutUint32(ip, uint32(addr.lo))
+	} else {
+		binary.BigEndian.PutUint64(ip, addr.hi)
+		binary.BigEndian.PutUint64(ip[8:], addr.lo)
+	}
+	for i := range mask {
+		switch {
+		case prefix >= 8:
+			mask[i] = 0xff
+			prefix -= 8
+		default:
+			mask[i] = ^byte(0xff >> prefix)
+			prefix = 0
+		}
+	}
+	return append(dst, net.IPNet{IP: ip, Mask: mask})
+}
+
+// appendRange appends the CIDR blocks covering the range lo to hi to dst.
+// The blocks are the same as those of splitRange4 and splitRange6, computed iteratively.
+func (ws *Workspace) appendRange(dst []net.IPNet, lo, hi uint128, width uint) []net.IPNet {
+	for {
+		// The largest block starting at lo and ending at or before hi
+		hostBits := lo.trailingZeros()
+		if hostBits > width {
+			hostBits = width
+		}
+		last := lo.or(hostmask128(hostBits))
+		for hi.less(last) {
+			hostBits--
+			last = lo.or(hostmask128(hostBits))
+		}
+		dst = ws.appendIPNet(dst, lo, width-hostBits, width)
+		if last == hi {
+			return dst
+		}
+		lo = last.addOne()
+	}
+}
+
+// loadBlockValues splits the networks into IPv4 and IPv6 blocks, appended to block4s and block6s.
+func loadBlockValues(nets []*net.IPNet, block4s, block6s cidrBlockValues) (cidrBlockValues, cidrBlockValues, error) {
+	for _, network := range nets {
+		// IPv4-mapped networks like MergeIPNets
+		ip4, mask, _ := ipv4Network(network)
+		prefix, _ := mask.Size()
+		if ip4 != nil {
+			if prefix > widthUInt32 {
+				return nil, nil, fmt.Errorf("Invalid mask size: %d", prefix)
+			}
+			first := uint128{0, uint64(ipv4ToUInt32(ip4))}
+			block4s = append(block4s, cidrBlockValue{first, uint128{0, first.lo | uint64(hostmask4(uint(prefix)))}})
+		} else if ip6 := network.IP.To16(); ip6 != nil {
+			first := ip6ToUInt128(ip6)
+			block6s = append(block6s, cidrBlockValue{first, first.or(hostmask128(widthUInt128 - uint(prefix)))})
+		} else {
+			return nil, nil, fmt.Errorf("Invalid IP address: %v", network.IP)
+		}
+	}
+	return block4s, block6s, nil
+}
+
+// MergeIPNets merges the networks like the MergeIPNets function and appends the result to dst.
+func (ws *Workspace) MergeIPNets(dst []net.IPNet, nets []*net.IPNet) ([]net.IPNet, error) {
+	var err error
+	ws.block4s, ws.block6s, err = loadBlockValues(nets, ws.block4s[:0], ws.block6s[:0])
+	if err != nil {
+		return dst, err
+	}
+
+	ws.block4s.coalesce()
+	for _, block := range ws.block4s {
+		dst = ws.appendRange(dst, block.first, block.last, widthUInt32)
+	}
+	ws.block6s.coalesce()
+	for _, block := range ws.block6s {
+		dst = ws.appendRange(dst, block.first, block.last, widthUInt128)
+	}
+	return dst, nil
+}
+
+// appendRemove appends the CIDR blocks of the coalesced blocks minus the coalesced removes to dst.
+func (ws *Workspace) appendRemove(dst []net.IPNet, blocks, removes cidrBlockValues, width uint) []net.IPNet {
+	j := 0
+	for _, block := range blocks {
+		// Skip remove-blocks entirely before the network-block
+		for j < len(removes) && removes[j].last.less(block.first) {
+			j++
+		}
+		lo := block.first
+		removed := false
+		for ; j < len(removes) && !block.last.less(removes[j].first); j++ {
+			if lo.less(removes[j].first) {
+				dst = ws.appendRange(dst, lo, removes[j].first.subOne(), width)
+			}
+			if !removes[j].last.less(block.last) {
+				// Remove-block covers the rest of the network-block, and may overlap the next one
+				removed = true
+				break
+			}
+			lo = removes[j].last.addOne()
+		}
+		if !removed {
+			dst = ws.appendRange(dst, lo, block.last, width)
+		}
+	}
+	return dst
+}
+
+// RemoveIPNets removes the second list of networks from the first like the RemoveIPNets function
+// and appends the result to dst.
+func (ws *Workspace) RemoveIPNets(dst []net.IPNet, nets, rmnets []*net.IPNet) ([]net.IPNet, error) {
+	if len(rmnets) == 0 {
+		// Like RemoveIPNets, return nets unchanged
+		for _, net := range nets {
+			dst = append(dst, *net)
+		}
+		return dst, nil
+	}
+
+	var err error
+	ws.block4s, ws.block6s, err = loadBlockValues(nets, ws.block4s[:0], ws.block6s[:0])
+	if err != nil {
+		return dst, err
+	}
+	ws.other4s, ws.other6s, err = loadBlockValues(rmnets, ws.other4s[:0], ws.other6s[:0])
+	if err != nil {
+		return dst, err
+	}
+
+	ws.block4s.coalesce()
+	ws.other4s.coalesce()
+	dst = ws.appendRemove(dst, ws.block4s, ws.other4s, widthUInt32)
+	ws.block6s.coalesce()
+	ws.other6s.coalesce()
+	dst = ws.appendRemove(dst, ws.block6s, ws.other6s, widthUInt128)
+	return dst, nil
+}
+
+// appendSubset appends the CIDR blocks of the overlaps of the coalesced blocks and subsets to dst.
+func (ws *Workspace) appendSubset(dst []net.IPNet, blocks, subsets cidrBlockValues, width uint) []net.IPNet {
+	i, j := 0, 0
+	for i < len(blocks) && j < len(subsets) {
+		lo, hi := blocks[i].first, blocks[i].last
+		if lo.less(subsets[j].first) {
+			lo = subsets[j].first
+		}
+		if subsets[j].last.less(hi) {
+			hi = subsets[j].last
+		}
+		if !hi.less(lo) {
+			dst = ws.appendRange(dst, lo, hi, width)
+		}
+		// Continue with the block that ends first
+		if blocks[i].last.less(subsets[j].last) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return dst
+}
+
+// SubsetIPNets keeps the parts of the first list of networks that overlap the second list like
+// the SubsetIPNets function and appends the result to dst.
+func (ws *Workspace) SubsetIPNets(dst []net.IPNet, nets, subsetnets []*net.IPNet) ([]net.IPNet, error) {
+	var err error
+	ws.block4s, ws.block6s, err = loadBlockValues(nets, ws.block4s[:0], ws.block6s[:0])
+	if err != nil {
+		return dst, err
+	}
+	ws.other4s, ws.other6s, err = loadBlockValues(subsetnets, ws.other4s[:0], ws.other6s[:0])
+	if err != nil {
+		return dst, err
+	}
+
+	ws.block4s.coalesce()
+	ws.other4s.coalesce()
+	dst = ws.appendSubset(dst, ws.block4s, ws.other4s, widthUInt32)
+	ws.block6s.coalesce()
+	ws.other6s.coalesce()
+	dst = ws.appendSubset(dst, ws.block6s, ws.other6s, widthUInt128)
+	return dst, nil
+}
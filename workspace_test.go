@@ -0,0 +1,159 @@
+// go test -v -run="TestWorkspace"
+// go test -run=NONE -bench="BenchmarkWorkspace" -benchmem
+
+package cidrman
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+// valueCIDRs returns the CIDRs of a list of networks by value.
+func valueCIDRs(nets []net.IPNet) []string {
+	var cidrs []string
+	for i := range nets {
+		cidrs = append(cidrs, nets[i].String())
+	}
+	return cidrs
+}
+
+func TestWorkspace(t *testing.T) {
+	type TestCase struct {
+		Nets    []*net.IPNet
+		Others  []*net.IPNet
+		Comment string
+	}
+
+	parse := func(cidrs ...string) []*net.IPNet {
+		var nets []*net.IPNet
+		for _, cidr := range cidrs {
+			_, network, _ := net.ParseCIDR(cidr)
+			nets = append(nets, network)
+		}
+		return nets
+	}
+
+	testCases := []TestCase{
+		{
+			Nets:    parse("0.0.0.0/0", "::/0"),
+			Others:  parse("255.255.255.255/32", "0.0.0.0/32", "::/128", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff/128"),
+			Comment: "Edges of the address space",
+		},
+		{
+			Nets:    parse("10.0.0.0/8", "192.0.2.0/24", "2001:db8::/32"),
+			Others:  parse("10.1.0.0/16", "10.1.0.0/24", "10.255.0.0/16", "192.0.2.128/25", "2001:db8:1::/48"),
+			Comment: "Overlapping removes and subsets",
+		},
+		{
+			Nets:    parse("::ffff:10.0.0.0/104", "::ffff:0.0.0.0/80", "11.0.0.0/8"),
+			Others:  parse("::ffff:10.1.0.0/112", "::/96"),
+			Comment: "IPv4-mapped networks",
+		},
+		{
+			Nets:    parse("10.0.0.0/24"),
+			Others:  nil,
+			Comment: "No removes and subsets",
+		},
+		{
+			Nets:    parse("192.0.2.0/25", "192.0.2.128/25", "2001:db8::/33", "2001:db8:8000::/33"),
+			Others:  parse("192.0.2.64/26", "2001:db8:4000::/34", "2001:db8:8000::/34"),
+			Comment: "Adjacent blocks",
+		},
+		{
+			Nets:    randomIPNets(2000, 4, 1),
+			Others:  randomIPNets(2000, 6, 2),
+			Comment: "Random networks",
+		},
+	}
+
+	ws := NewWorkspace()
+	var output []net.IPNet
+	for _, testCase := range testCases {
+		ws.Reset()
+
+		expected, _ := MergeIPNets(testCase.Nets)
+		output, err := ws.MergeIPNets(output[:0], testCase.Nets)
+		if err != nil {
+			t.Errorf("MergeIPNets(%s) failed: %s", testCase.Comment, err.Error())
+		} else if !reflect.DeepEqual(ipNets(expected).toCIDRs(), valueCIDRs(output)) {
+			t.Errorf("MergeIPNets(%s) expected: %#v, got: %#v", testCase.Comment, ipNets(expected).toCIDRs(), valueCIDRs(output))
+		}
+
+		expected, _ = RemoveIPNets(testCase.Nets, testCase.Others)
+		output, err = ws.RemoveIPNets(output[:0], testCase.Nets, testCase.Others)
+		if err != nil {
+			t.Errorf("RemoveIPNets(%s) failed: %s", testCase.Comment, err.Error())
+		} else if !reflect.DeepEqual(ipNets(expected).toCIDRs(), valueCIDRs(output)) {
+			t.Errorf("RemoveIPNets(%s) expected: %#v, got: %#v", testCase.Comment, ipNets(expected).toCIDRs(), valueCIDRs(output))
+		}
+
+		expected, _ = SubsetIPNets(testCase.Nets, testCase.Others)
+		output, err = ws.SubsetIPNets(output[:0], testCase.Nets, testCase.Others)
+		if err != nil {
+			t.Errorf("SubsetIPNets(%s) failed: %s", testCase.Comment, err.Error())
+		} else if !reflect.DeepEqual(ipNets(expected).toCIDRs(), valueCIDRs(output)) {
+			t.Errorf("SubsetIPNets(%s) expected: %#v, got: %#v", testCase.Comment, ipNets(expected).toCIDRs(), valueCIDRs(output))
+		}
+	}
+
+	// Outputs stay valid when the arena grows, until Reset
+	ws = &Workspace{}
+	first, _ := ws.MergeIPNets(nil, parse("192.0.2.0/24"))
+	ws.MergeIPNets(nil, randomIPNets(1000, 8, 3))
+	if first[0].String() != "192.0.2.0/24" {
+		t.Errorf("MergeIPNets() output changed to: %s", first[0].String())
+	}
+
+	if _, err := ws.MergeIPNets(nil, []*net.IPNet{{IP: net.IP{192, 0, 2, 0}, Mask: net.CIDRMask(120, 128)}}); err == nil {
+		t.Errorf("MergeIPNets() expected error for IPv4 address with IPv6 mask")
+	}
+	if _, err := ws.MergeIPNets(nil, []*net.IPNet{{}}); err == nil {
+		t.Errorf("MergeIPNets() expected error for missing IP address")
+	}
+}
+
+func TestWorkspaceAllocs(t *testing.T) {
+	nets := randomIPNets(1000, 8, 1)
+	others := randomIPNets(1000, 12, 2)
+	ws := NewWorkspace()
+	var output []net.IPNet
+
+	allocs := testing.AllocsPerRun(10, func() {
+		ws.Reset()
+		output, _ = ws.MergeIPNets(output[:0], nets)
+		output, _ = ws.RemoveIPNets(output, nets, others)
+		output, _ = ws.SubsetIPNets(output, nets, others)
+	})
+	if allocs != 0 {
+		t.Errorf("Workspace operations allocated %v times per run, expected 0", allocs)
+	}
+}
+
+func benchmarkWorkspace(b *testing.B, op func(ws *Workspace, dst []net.IPNet, nets, others []*net.IPNet) ([]net.IPNet, error)) {
+	nets := randomIPNets(100000, 8, 1)
+	others := randomIPNets(100000, 12, 2)
+	ws := NewWorkspace()
+	// Grow the buffers of the workspace and the output to their steady state size
+	output, _ := op(ws, nil, nets, others)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ws.Reset()
+		output, _ = op(ws, output[:0], nets, others)
+	}
+}
+
+func BenchmarkWorkspaceMergeIPNets(b *testing.B) {
+	benchmarkWorkspace(b, func(ws *Workspace, dst []net.IPNet, nets, others []*net.IPNet) ([]net.IPNet, error) {
+		return ws.MergeIPNets(dst, nets)
+	})
+}
+
+func BenchmarkWorkspaceRemoveIPNets(b *testing.B) {
+	benchmarkWorkspace(b, (*Workspace).RemoveIPNets)
+}
+
+func BenchmarkWorkspaceSubsetIPNets(b *testing.B) {
+	benchmarkWorkspace(b, (*Workspace).SubsetIPNets)
+}